@@ -4,6 +4,8 @@ import (
 	"context"
 	"flag"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"observer/internal/manager"
 	"observer/internal/settings"
@@ -12,16 +14,43 @@ import (
 func main() {
 	showVer := flag.Bool("v", false, "show version")
 	debugMode := flag.Bool("debug", false, "debug mode")
+	configFile := flag.String("c", "config.yml", "config filepath")
+	settingsFile := flag.String("s", "", "settings filepath (JSON, YAML or SQLite .db), settings are kept in memory when empty")
 	flag.Parse()
 	if *showVer {
 		print(settings.Version())
 		os.Exit(0)
 	}
 
-	m := manager.New()
+	m, err := manager.New(*configFile, *settingsFile)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
 	if *debugMode {
 		println(settings.Version())
 	}
-	m.Start(context.Background())
+	go reloadOnHangup(m)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// the second signal kills the observer without waiting for the shutdown
+		<-ctx.Done()
+		stop()
+	}()
+	if err := m.Start(ctx); err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
 	println("exit")
 }
+
+func reloadOnHangup(m *manager.Data) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := m.Reload(context.Background()); err != nil {
+			m.Logger.Error(context.Background(), err, "reload config, previous items are kept")
+		}
+	}
+}
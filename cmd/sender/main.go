@@ -27,7 +27,7 @@ func main() {
 		if errors.As(err, &flagsErr) && flagsErr.Type == flags.ErrHelp {
 			os.Exit(0)
 		}
-		log.Fatalf("flags: %w", err)
+		log.Fatalf("flags: %v", err)
 	}
 }
 
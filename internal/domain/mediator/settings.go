@@ -15,15 +15,15 @@ type SettingsEvent struct {
 }
 
 type SettingsItem struct {
-	Id          int    `db:"id"`
-	Name        string `db:"name"`
-	Value       string `db:"value"`
-	Group       string `db:"group"`
-	Type        string `db:"type"`
-	Data        string `db:"data"`
-	UserId      int    `db:"user_id"`
-	Title       string `db:"title"`
-	Description string `db:"description"`
+	Id          int    `db:"id" json:"id" yaml:"id"`
+	Name        string `db:"name" json:"name" yaml:"name"`
+	Value       string `db:"value" json:"value" yaml:"value"`
+	Group       string `db:"group" json:"group" yaml:"group"`
+	Type        string `db:"type" json:"type" yaml:"type"`
+	Data        string `db:"data" json:"data" yaml:"data"`
+	UserId      int    `db:"user_id" json:"user_id" yaml:"user_id"`
+	Title       string `db:"title" json:"title" yaml:"title"`
+	Description string `db:"description" json:"description" yaml:"description"`
 }
 
 const (
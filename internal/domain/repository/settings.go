@@ -7,5 +7,7 @@ import (
 
 type Settings interface {
 	GetList(requestFilter.Filter) ([]models.SettingsItem, error)
+	Create(models.SettingsItem) (models.SettingsItem, error)
 	Update(models.SettingsItem) (models.SettingsItem, error)
+	Delete(name string) error
 }
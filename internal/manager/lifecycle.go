@@ -0,0 +1,296 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const readyPollInterval = 100 * time.Millisecond
+
+// Service is a subsystem run by the manager
+type Service interface {
+	// Init prepares the service, it is called once before the first start
+	Init(ctx context.Context) error
+	// Start runs the service in background and returns
+	Start(ctx context.Context) error
+	// Stop stops the service within the ctx deadline
+	Stop(ctx context.Context) error
+	// Health returns nil when the service is ready
+	Health(ctx context.Context) error
+}
+
+// unit is the registered service with its dependencies
+type unit struct {
+	name      string
+	service   Service
+	dependsOn []string
+	running   bool
+	pending   chan error
+	locked    chan struct{}
+}
+
+// lock takes the unit until ctx is done, false means a start or stop of the unit is still running
+func (u *unit) lock(ctx context.Context) bool {
+	select {
+	case u.locked <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (u *unit) unlock() {
+	<-u.locked
+}
+
+// settle waits for the start that exceeded the timeout, the unit is running when it succeeded,
+// the caller holds the unit lock
+func (u *unit) settle(ctx context.Context) {
+	if u.pending == nil {
+		return
+	}
+	select {
+	case err := <-u.pending:
+		u.pending = nil
+		if err == nil {
+			u.running = true
+		}
+	case <-ctx.Done():
+	}
+}
+
+// Register adds the service started after its dependencies and stopped before them
+func (d *Data) Register(name string, service Service, dependsOn ...string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, registered := range d.units {
+		if registered.name == name {
+			return fmt.Errorf("service %s is already registered", name)
+		}
+	}
+	d.units = append(d.units, &unit{
+		name:      name,
+		service:   service,
+		dependsOn: dependsOn,
+		locked:    make(chan struct{}, 1),
+	})
+	return nil
+}
+
+// ordered returns units sorted by dependencies, registration order is kept otherwise
+func (d *Data) ordered() ([]*unit, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	byName := make(map[string]*unit, len(d.units))
+	for _, u := range d.units {
+		byName[u.name] = u
+	}
+	result := make([]*unit, 0, len(d.units))
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(u *unit) error
+	visit = func(u *unit) error {
+		if visited[u.name] {
+			return nil
+		}
+		if visiting[u.name] {
+			return fmt.Errorf("service %s has a dependency cycle", u.name)
+		}
+		visiting[u.name] = true
+		for _, name := range u.dependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("service %s depends on unknown service %s", u.name, name)
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		visiting[u.name] = false
+		visited[u.name] = true
+		result = append(result, u)
+		return nil
+	}
+	for _, u := range d.units {
+		if err := visit(u); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// startUnits inits and starts units in order, started ones including the failed one are stopped
+// when any of them fails
+func (d *Data) startUnits(ctx context.Context, units []*unit) error {
+	timeout := d.Services.settings.GetValueSeconds("OBSERVER_MANAGER_START_TIMEOUT_SEC", 30)
+	for _, u := range units {
+		initCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := u.service.Init(initCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("service %s init: %w", u.name, err)
+		}
+	}
+	for i, u := range units {
+		err := fmt.Errorf("service %s start: %w", u.name, ctx.Err())
+		if u.lock(ctx) {
+			err = d.startUnit(ctx, u, timeout)
+			u.unlock()
+		}
+		if err != nil {
+			stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+			defer cancel()
+			return errors.Join(err, d.stopUnits(stopCtx, units[:i+1]))
+		}
+	}
+	return nil
+}
+
+// startUnit starts the service and waits until it is healthy or ctx is done, a start exceeding
+// the timeout is kept pending to be stopped once it returns, the caller holds the unit lock
+func (d *Data) startUnit(ctx context.Context, u *unit, timeout time.Duration) error {
+	if u.pending != nil {
+		return fmt.Errorf("service %s start: the previous start is still running", u.name)
+	}
+	d.Logger.Info(ctx, "start service", "service", u.name)
+	started := make(chan error, 1)
+	go func() {
+		started <- u.service.Start(ctx)
+	}()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	select {
+	case err := <-started:
+		if err != nil {
+			return fmt.Errorf("service %s start: %w", u.name, err)
+		}
+	case <-deadline.C:
+		u.pending = started
+		return fmt.Errorf("service %s start: timeout %s", u.name, timeout)
+	case <-ctx.Done():
+		u.pending = started
+		return fmt.Errorf("service %s start: %w", u.name, ctx.Err())
+	}
+	u.running = true
+	for {
+		err := u.service.Health(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-deadline.C:
+			return fmt.Errorf("service %s is not ready in %s: %w", u.name, timeout, err)
+		case <-ctx.Done():
+			return fmt.Errorf("service %s is not ready: %w", u.name, ctx.Err())
+		case <-time.After(readyPollInterval):
+		}
+	}
+}
+
+// stopUnits stops running units in reverse order, pending starts and restarts in progress
+// are awaited within ctx
+func (d *Data) stopUnits(ctx context.Context, units []*unit) error {
+	errs := make([]error, 0)
+	for i := len(units) - 1; i >= 0; i-- {
+		u := units[i]
+		if !u.lock(ctx) {
+			errs = append(errs, fmt.Errorf("service %s stop: restart is still running: %w", u.name, ctx.Err()))
+			continue
+		}
+		u.settle(ctx)
+		if u.pending != nil {
+			errs = append(errs, fmt.Errorf("service %s stop: start is still running", u.name))
+		}
+		if u.running {
+			d.Logger.Info(ctx, "stop service", "service", u.name)
+			if err := u.service.Stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("service %s stop: %w", u.name, err))
+			}
+			u.running = false
+		}
+		u.unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// supervision is the health check interval and the restart backoff of supervised units
+type supervision struct {
+	interval   time.Duration
+	timeout    time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// supervise restarts the unit by the supervision settings until ctx is done
+func (d *Data) supervise(ctx context.Context, u *unit) {
+	settings := d.Services.settings
+	d.superviseWith(ctx, u, supervision{
+		interval:   settings.GetValueSeconds("OBSERVER_MANAGER_HEALTH_SEC", 5),
+		timeout:    settings.GetValueSeconds("OBSERVER_MANAGER_START_TIMEOUT_SEC", 30),
+		minBackoff: settings.GetValueSeconds("OBSERVER_MANAGER_RESTART_BACKOFF_SEC", 1),
+		maxBackoff: settings.GetValueSeconds("OBSERVER_MANAGER_RESTART_MAX_BACKOFF_SEC", 60),
+	})
+}
+
+// superviseWith restarts the unit with a growing backoff while its health check fails until ctx is done
+func (d *Data) superviseWith(ctx context.Context, u *unit, policy supervision) {
+	backoff := policy.minBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(policy.interval):
+		}
+		err := u.service.Health(ctx)
+		if err == nil {
+			backoff = policy.minBackoff
+			continue
+		}
+		d.Logger.Error(ctx, err, "service is unhealthy, restarting", "service", u.name, "backoff", backoff.String())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, policy.maxBackoff)
+		if !u.lock(ctx) {
+			return
+		}
+		if ctx.Err() == nil {
+			d.restartUnit(ctx, u, policy.timeout)
+		}
+		u.unlock()
+	}
+}
+
+// restartUnit stops and starts the unit, the caller holds the unit lock
+func (d *Data) restartUnit(ctx context.Context, u *unit, timeout time.Duration) {
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	u.settle(stopCtx)
+	if u.running {
+		if err := u.service.Stop(stopCtx); err != nil {
+			d.Logger.Error(ctx, err, "stop unhealthy service", "service", u.name)
+		}
+		u.running = false
+	}
+	if err := d.startUnit(ctx, u, timeout); err != nil {
+		d.Logger.Error(ctx, err, "restart service", "service", u.name)
+	}
+}
+
+// Health returns the joined errors of services that are not ready
+func (d *Data) Health(ctx context.Context) error {
+	d.mutex.Lock()
+	units := append([]*unit{}, d.units...)
+	d.mutex.Unlock()
+	errs := make([]error, 0)
+	for _, u := range units {
+		if err := u.service.Health(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,260 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	models "observer/internal/domain/mediator"
+	"observer/internal/logger"
+	"observer/internal/settings"
+	"observer/pkg/mediator"
+)
+
+// testService records calls, start waits for startDelay and health fails while unhealthy is set
+// or until the service is started healthyAfter times
+type testService struct {
+	name         string
+	startDelay   time.Duration
+	unhealthy    bool
+	healthyAfter int
+	starts       []time.Time
+	stops        []time.Time
+	calls        *[]string
+	mutex        *sync.Mutex
+}
+
+func (s *testService) record(call string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	*s.calls = append(*s.calls, s.name+"."+call)
+}
+
+func (s *testService) Init(ctx context.Context) error {
+	s.record("init")
+	return nil
+}
+
+func (s *testService) Start(ctx context.Context) error {
+	time.Sleep(s.startDelay)
+	s.record("start")
+	s.mutex.Lock()
+	s.starts = append(s.starts, time.Now())
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *testService) Stop(ctx context.Context) error {
+	s.record("stop")
+	s.mutex.Lock()
+	s.stops = append(s.stops, time.Now())
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *testService) Health(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.unhealthy || len(s.starts) < s.healthyAfter {
+		return errors.New("not ready")
+	}
+	return nil
+}
+
+func (s *testService) startCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.starts)
+}
+
+func testManager(t *testing.T) (*Data, *[]string, *sync.Mutex) {
+	t.Helper()
+	loggerService := logger.New(nil, nil)
+	repo := settings.NewSettingsRepo()
+	if _, err := repo.Create(models.SettingsItem{Name: "OBSERVER_MANAGER_START_TIMEOUT_SEC", Value: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	d := &Data{
+		dispatcher: mediator.NewDispatcher(),
+		Logger:     loggerService,
+		Services:   Services{settings: settings.New(mediator.NewDispatcher(), loggerService, repo)},
+		units:      make([]*unit, 0),
+		mutex:      &sync.Mutex{},
+	}
+	return d, &[]string{}, &sync.Mutex{}
+}
+
+func (d *Data) testRegister(t *testing.T, service *testService, dependsOn ...string) {
+	t.Helper()
+	if err := d.Register(service.name, service, dependsOn...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testCalls(calls *[]string, mutex *sync.Mutex) []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return append([]string{}, *calls...)
+}
+
+func TestStartUnitsOrder(t *testing.T) {
+	d, calls, mutex := testManager(t)
+	d.testRegister(t, &testService{name: "api", calls: calls, mutex: mutex}, "db")
+	d.testRegister(t, &testService{name: "db", calls: calls, mutex: mutex})
+	units, err := d.ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = d.startUnits(ctx, units); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.stopUnits(ctx, units); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"db.init", "api.init", "db.start", "api.start", "api.stop", "db.stop"}
+	if got := testCalls(calls, mutex); !slices.Equal(got, want) {
+		t.Errorf("calls %v, want %v", got, want)
+	}
+}
+
+func TestStartTimeoutStopsLateService(t *testing.T) {
+	d, calls, mutex := testManager(t)
+	d.testRegister(t, &testService{name: "db", calls: calls, mutex: mutex})
+	d.testRegister(t, &testService{name: "slow", startDelay: 1500 * time.Millisecond, calls: calls, mutex: mutex}, "db")
+	units, err := d.ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.startUnits(context.Background(), units); err == nil {
+		t.Fatal("start timeout: want an error")
+	}
+	want := []string{"db.init", "slow.init", "db.start", "slow.start", "slow.stop", "db.stop"}
+	if got := testCalls(calls, mutex); !slices.Equal(got, want) {
+		t.Errorf("calls %v, want %v", got, want)
+	}
+}
+
+func TestStartNotReadyStopsService(t *testing.T) {
+	d, calls, mutex := testManager(t)
+	d.testRegister(t, &testService{name: "sick", unhealthy: true, calls: calls, mutex: mutex})
+	units, err := d.ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.startUnits(context.Background(), units); err == nil {
+		t.Fatal("not ready: want an error")
+	}
+	want := []string{"sick.init", "sick.start", "sick.stop"}
+	if got := testCalls(calls, mutex); !slices.Equal(got, want) {
+		t.Errorf("calls %v, want %v", got, want)
+	}
+}
+
+func TestOrderedErrors(t *testing.T) {
+	d, calls, mutex := testManager(t)
+	d.testRegister(t, &testService{name: "a", calls: calls, mutex: mutex}, "b")
+	d.testRegister(t, &testService{name: "b", calls: calls, mutex: mutex}, "a")
+	if _, err := d.ordered(); err == nil {
+		t.Error("cycle: want an error")
+	}
+	d, calls, mutex = testManager(t)
+	d.testRegister(t, &testService{name: "a", calls: calls, mutex: mutex}, "missing")
+	if _, err := d.ordered(); err == nil {
+		t.Error("unknown dependency: want an error")
+	}
+	if err := d.Register("a", &testService{name: "a", calls: calls, mutex: mutex}); err == nil {
+		t.Error("duplicate: want an error")
+	}
+}
+
+func TestSuperviseRestartsWithBackoff(t *testing.T) {
+	d, calls, mutex := testManager(t)
+	service := &testService{name: "flaky", healthyAfter: 3, calls: calls, mutex: mutex}
+	d.testRegister(t, service)
+	units, err := d.ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	units[0].running = true
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	begin := time.Now()
+	policy := supervision{interval: 10 * time.Millisecond, timeout: 30 * time.Millisecond, minBackoff: 50 * time.Millisecond, maxBackoff: 200 * time.Millisecond}
+	go func() {
+		defer close(done)
+		d.superviseWith(ctx, units[0], policy)
+	}()
+	for deadline := time.Now().Add(5 * time.Second); service.startCount() < 3 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the healthy service is not restarted again
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	want := []string{"flaky.stop", "flaky.start", "flaky.stop", "flaky.start", "flaky.stop", "flaky.start"}
+	if got := testCalls(calls, mutex); !slices.Equal(got, want) {
+		t.Fatalf("calls %v, want %v", got, want)
+	}
+	// every restart waits for the failed health check and the backoff doubled after the previous restart
+	previous := []time.Time{begin, service.starts[0], service.starts[1]}
+	for i, backoff := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond} {
+		wait := policy.interval + backoff
+		if i > 0 {
+			wait += policy.timeout
+		}
+		if gap := service.stops[i].Sub(previous[i]); gap < wait {
+			t.Errorf("restart %d after %s, want at least %s", i+1, gap, wait)
+		}
+	}
+}
+
+func TestShutdownDuringRestart(t *testing.T) {
+	d, calls, mutex := testManager(t)
+	service := &testService{name: "sick", unhealthy: true, calls: calls, mutex: mutex}
+	d.testRegister(t, service)
+	units, err := d.ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	units[0].running = true
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	policy := supervision{interval: 10 * time.Millisecond, timeout: time.Minute, minBackoff: 10 * time.Millisecond, maxBackoff: 10 * time.Millisecond}
+	go func() {
+		defer close(done)
+		d.superviseWith(ctx, units[0], policy)
+	}()
+	// the restart waits for the service to become ready until the start timeout
+	for deadline := time.Now().Add(5 * time.Second); service.startCount() < 1 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+	defer stopCancel()
+	begin := time.Now()
+	if err = d.stopUnits(stopCtx, units); err != nil {
+		t.Errorf("stop: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("stop took %s, want the restart canceled", elapsed)
+	}
+	<-done
+	want := []string{"sick.stop", "sick.start", "sick.stop"}
+	if got := testCalls(calls, mutex); !slices.Equal(got, want) {
+		t.Errorf("calls %v, want %v", got, want)
+	}
+
+	// a unit locked past the deadline is reported instead of blocking the shutdown
+	units[0].lock(context.Background())
+	defer units[0].unlock()
+	expiredCtx, expiredCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer expiredCancel()
+	if err = d.stopUnits(expiredCtx, units); err == nil {
+		t.Error("locked unit: want an error")
+	}
+}
@@ -2,6 +2,8 @@ package manager
 
 import (
 	"context"
+	"errors"
+	"sync"
 
 	"observer/internal/domain/services"
 	"observer/internal/logger"
@@ -14,6 +16,8 @@ type Data struct {
 	dispatcher *mediator.Dispatcher
 	Logger     *logger.Logger
 	Services   Services
+	units      []*unit
+	mutex      *sync.Mutex
 }
 
 type Services struct {
@@ -21,24 +25,68 @@ type Services struct {
 	pinger   *pinger.Data
 }
 
-var onExit chan bool
-
-func New() *Data {
+// New builds the manager, settings are stored in settingsFile or in memory when it is empty
+func New(configFile, settingsFile string) (*Data, error) {
+	settingsRepo, err := settings.NewRepo(settingsFile)
+	if err != nil {
+		return nil, err
+	}
 	dispatcher := mediator.NewDispatcher()
 	loggerService := logger.New(nil, nil)
-	settingsService := settings.New(dispatcher, loggerService)
-	return &Data{
+	settingsService := settings.New(dispatcher, loggerService, settingsRepo)
+	d := &Data{
 		dispatcher: dispatcher,
 		Logger:     loggerService,
 		Services: Services{
 			settings: settingsService,
-			pinger:   pinger.New(dispatcher, loggerService, settingsService),
+			pinger:   pinger.New(dispatcher, loggerService, settingsService, configFile),
 		},
+		units: make([]*unit, 0),
+		mutex: &sync.Mutex{},
+	}
+	if err = d.Register("pinger", d.Services.pinger); err != nil {
+		return nil, err
 	}
+	return d, nil
 }
 
-func (d *Data) Start(ctx context.Context) {
+// Start runs registered services by dependencies and supervises them until ctx is done,
+// then stops them within OBSERVER_SHUTDOWN_TIMEOUT_SEC
+func (d *Data) Start(ctx context.Context) error {
 	d.Logger.Debug(ctx, "start manager")
-	d.Services.pinger.Start(ctx)
-	<-onExit
+	units, err := d.ordered()
+	if err != nil {
+		return err
+	}
+	if err = d.startUnits(ctx, units); err != nil {
+		return err
+	}
+	d.Logger.Info(ctx, "services are ready", "count", len(units))
+	for _, u := range units {
+		go d.supervise(ctx, u)
+	}
+	<-ctx.Done()
+	return d.Shutdown(context.WithoutCancel(ctx))
+}
+
+// Shutdown stops services in reverse order and then the dispatcher,
+// an error is returned when the deadline is exceeded
+func (d *Data) Shutdown(ctx context.Context) error {
+	timeout := d.Services.settings.GetValueSeconds("OBSERVER_SHUTDOWN_TIMEOUT_SEC", 30)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d.Logger.Info(ctx, "shutdown", "timeout", timeout.String())
+	units, err := d.ordered()
+	if err != nil {
+		return err
+	}
+	return errors.Join(
+		d.stopUnits(ctx, units),
+		d.dispatcher.Shutdown(ctx),
+	)
+}
+
+// Reload applies the changed config without restarting the observer
+func (d *Data) Reload(ctx context.Context) error {
+	return d.Services.pinger.Reload(ctx)
 }
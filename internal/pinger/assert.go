@@ -0,0 +1,319 @@
+package pinger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+const (
+	OperatorExists    = "exists"
+	OperatorEqual     = "="
+	OperatorNotEqual  = "!="
+	OperatorLess      = "<"
+	OperatorLessEq    = "<="
+	OperatorGreater   = ">"
+	OperatorGreaterEq = ">="
+	OperatorContains  = "contains"
+	OperatorRegex     = "regex"
+)
+
+const (
+	defaultStatusMin   = 200
+	defaultStatusMax   = 299
+	assertionSeparator = "; "
+)
+
+// jsonPathLanguage is JSONPath with the full gval language, filters like ?(@.id > 1) need its operators
+var jsonPathLanguage = gval.Full(jsonpath.Language())
+
+var grepOperators = []string{
+	OperatorExists, OperatorEqual, OperatorNotEqual, OperatorLess, OperatorLessEq,
+	OperatorGreater, OperatorGreaterEq, OperatorContains, OperatorRegex,
+}
+
+type AssertionResult struct {
+	Name     string `json:"name" yaml:"name"`
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+	Passed   bool   `json:"passed" yaml:"passed"`
+}
+
+func (a AssertionResult) String() string {
+	return fmt.Sprintf("%s expected %s, got %s", a.Name, a.Expected, a.Actual)
+}
+
+// Assert checks the response by every configured expectation,
+// the 2xx status range is expected when no status is configured
+func (r Response) Assert(statusCode int, contentType, body string) []AssertionResult {
+	assertions := r.Status.assert(statusCode)
+	if r.Body == nil {
+		return assertions
+	}
+	if r.Body.Full != "" {
+		assertions = append(assertions, AssertionResult{
+			Name:     "body.full",
+			Expected: strconv.Quote(shorten(r.Body.Full)),
+			Actual:   strconv.Quote(shorten(body)),
+			Passed:   body == r.Body.Full,
+		})
+	}
+	if r.Body.Contain != "" {
+		assertions = append(assertions, AssertionResult{
+			Name:     "body.contain",
+			Expected: strconv.Quote(r.Body.Contain),
+			Actual:   strconv.Quote(shorten(body)),
+			Passed:   strings.Contains(body, r.Body.Contain),
+		})
+	}
+	if r.Body.Regex != "" {
+		assertion := AssertionResult{
+			Name:     "body.regex",
+			Expected: r.Body.Regex,
+			Actual:   strconv.Quote(shorten(body)),
+		}
+		if re, err := regexp.Compile(r.Body.Regex); err != nil {
+			assertion.Actual = err.Error()
+		} else {
+			assertion.Passed = re.MatchString(body)
+		}
+		assertions = append(assertions, assertion)
+	}
+	if r.Body.Grep != nil {
+		assertions = append(assertions, r.Body.Grep.assert(contentType, body))
+	}
+	return assertions
+}
+
+func (s ItemResultStatus) assert(code int) []AssertionResult {
+	actual := strconv.Itoa(code)
+	assertions := make([]AssertionResult, 0)
+	if s.Code != 0 {
+		assertions = append(assertions, AssertionResult{
+			Name:     "status.code",
+			Expected: strconv.Itoa(s.Code),
+			Actual:   actual,
+			Passed:   code == s.Code,
+		})
+	}
+	if s.Min != 0 || s.Max != 0 {
+		assertions = append(assertions, AssertionResult{
+			Name:     "status.range",
+			Expected: fmt.Sprintf("[%d, %d]", s.Min, s.Max),
+			Actual:   actual,
+			Passed:   code >= s.Min && (s.Max == 0 || code <= s.Max),
+		})
+	}
+	if len(s.List) > 0 {
+		assertion := AssertionResult{
+			Name:     "status.list",
+			Expected: fmt.Sprintf("%v", s.List),
+			Actual:   actual,
+		}
+		for _, listCode := range s.List {
+			if code == listCode {
+				assertion.Passed = true
+			}
+		}
+		assertions = append(assertions, assertion)
+	}
+	if len(assertions) == 0 {
+		assertions = append(assertions, AssertionResult{
+			Name:     "status.range",
+			Expected: fmt.Sprintf("[%d, %d]", defaultStatusMin, defaultStatusMax),
+			Actual:   actual,
+			Passed:   code >= defaultStatusMin && code <= defaultStatusMax,
+		})
+	}
+	return assertions
+}
+
+func (g Grep) assert(contentType, body string) AssertionResult {
+	assertion := AssertionResult{
+		Name:     "body.grep.json_path",
+		Expected: strings.TrimSpace(fmt.Sprintf("%s %s %s", g.JsonPath, g.operator(), g.Value)),
+	}
+	var values []string
+	var err error
+	if g.Xpath != "" {
+		assertion.Name = "body.grep.xpath"
+		assertion.Expected = strings.TrimSpace(fmt.Sprintf("%s %s %s", g.Xpath, g.operator(), g.Value))
+		values, err = grepXpath(g.Xpath, contentType, body)
+	} else {
+		values, err = grepJsonPath(g.JsonPath, body)
+	}
+	if err != nil {
+		assertion.Actual = err.Error()
+		return assertion
+	}
+	assertion.Actual = strings.Join(values, ", ")
+	if len(values) == 0 {
+		assertion.Actual = "nothing found"
+		assertion.Passed = g.operator() == OperatorNotEqual
+		return assertion
+	}
+	assertion.Passed = true
+	for _, value := range values {
+		passed, err := compare(value, g.operator(), g.Value)
+		if err != nil {
+			assertion.Actual = err.Error()
+		}
+		assertion.Passed = assertion.Passed && passed
+	}
+	return assertion
+}
+
+func (g Grep) operator() string {
+	if g.Operator != "" {
+		return g.Operator
+	}
+	if g.Value == "" {
+		return OperatorExists
+	}
+	return OperatorEqual
+}
+
+func grepJsonPath(path, body string) ([]string, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return nil, fmt.Errorf("parse json: %w", err)
+	}
+	evaluable, err := compileJsonPath(path)
+	if err != nil {
+		return nil, err
+	}
+	found, err := evaluable(context.Background(), data)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "unknown key") || strings.HasSuffix(err.Error(), "out of bounds") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	multiple := strings.Contains(path, "*") || strings.Contains(path, "..") ||
+		strings.Contains(path, "?(") || strings.ContainsAny(path, ",:")
+	if list, ok := found.([]interface{}); ok && multiple {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, jsonString(item))
+		}
+		return values, nil
+	}
+	return []string{jsonString(found)}, nil
+}
+
+func compileJsonPath(path string) (gval.Evaluable, error) {
+	return jsonPathLanguage.NewEvaluable(path)
+}
+
+func jsonString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return "null"
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func grepXpath(expr, contentType, body string) ([]string, error) {
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	var navigator xpath.NodeNavigator
+	if strings.Contains(contentType, "html") {
+		doc, err := htmlquery.Parse(strings.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("parse html: %w", err)
+		}
+		navigator = htmlquery.CreateXPathNavigator(doc)
+	} else {
+		doc, err := xmlquery.Parse(strings.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("parse xml: %w", err)
+		}
+		navigator = xmlquery.CreateXPathNavigator(doc)
+	}
+	switch result := compiled.Evaluate(navigator).(type) {
+	case *xpath.NodeIterator:
+		values := make([]string, 0)
+		for result.MoveNext() {
+			values = append(values, strings.TrimSpace(result.Current().Value()))
+		}
+		return values, nil
+	case float64:
+		return []string{strconv.FormatFloat(result, 'f', -1, 64)}, nil
+	default:
+		return []string{fmt.Sprintf("%v", result)}, nil
+	}
+}
+
+// compare applies the operator to the found and the expected values,
+// ordering operators require both values to be numbers
+func compare(actual, operator, expected string) (bool, error) {
+	switch operator {
+	case OperatorExists:
+		return true, nil
+	case OperatorEqual, OperatorNotEqual:
+		equal := actual == expected
+		actualNumber, actualErr := strconv.ParseFloat(actual, 64)
+		expectedNumber, expectedErr := strconv.ParseFloat(expected, 64)
+		if actualErr == nil && expectedErr == nil {
+			equal = actualNumber == expectedNumber
+		}
+		return equal == (operator == OperatorEqual), nil
+	case OperatorContains:
+		return strings.Contains(actual, expected), nil
+	case OperatorRegex:
+		return regexp.MatchString(expected, actual)
+	}
+	actualNumber, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return false, fmt.Errorf("%q is not a number", actual)
+	}
+	expectedNumber, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return false, fmt.Errorf("%q is not a number", expected)
+	}
+	switch operator {
+	case OperatorLess:
+		return actualNumber < expectedNumber, nil
+	case OperatorLessEq:
+		return actualNumber <= expectedNumber, nil
+	case OperatorGreater:
+		return actualNumber > expectedNumber, nil
+	case OperatorGreaterEq:
+		return actualNumber >= expectedNumber, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", operator)
+}
+
+func failedAssertions(assertions []AssertionResult) string {
+	failed := make([]string, 0)
+	for _, assertion := range assertions {
+		if !assertion.Passed {
+			failed = append(failed, assertion.String())
+		}
+	}
+	return strings.Join(failed, assertionSeparator)
+}
+
+func shorten(text string) string {
+	const limit = 100
+	if len(text) <= limit {
+		return text
+	}
+	return text[:limit] + "..."
+}
@@ -0,0 +1,146 @@
+package pinger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResponseAssert(t *testing.T) {
+	cases := []struct {
+		name     string
+		response Response
+		status   int
+		body     string
+		failed   []string
+	}{
+		{"default range", Response{}, 204, "", nil},
+		{"default range failed", Response{}, 301, "", []string{"status.range"}},
+		{"code", Response{Status: ItemResultStatus{Code: 201}}, 200, "", []string{"status.code"}},
+		{"open range", Response{Status: ItemResultStatus{Min: 400}}, 503, "", nil},
+		{"list", Response{Status: ItemResultStatus{List: []int{200, 304}}}, 304, "", nil},
+		{"list failed", Response{Status: ItemResultStatus{List: []int{200, 304}}}, 302, "", []string{"status.list"}},
+		{"full", Response{Body: &ResponseBody{Full: "ok"}}, 200, "ok", nil},
+		{"full failed", Response{Body: &ResponseBody{Full: "ok"}}, 200, "ok\n", []string{"body.full"}},
+		{"contain", Response{Body: &ResponseBody{Contain: "up"}}, 200, "status: up", nil},
+		{"regex", Response{Body: &ResponseBody{Regex: `^v\d+$`}}, 200, "v12", nil},
+		{"invalid regex", Response{Body: &ResponseBody{Regex: `(`}}, 200, "(", []string{"body.regex"}},
+		{"every body check", Response{Body: &ResponseBody{Contain: "x", Regex: "y"}}, 500, "z", []string{"status.range", "body.contain", "body.regex"}},
+	}
+	for _, c := range cases {
+		failed := make([]string, 0)
+		for _, assertion := range c.response.Assert(c.status, "text/plain", c.body) {
+			if !assertion.Passed {
+				failed = append(failed, assertion.Name)
+			}
+		}
+		if len(failed) != len(c.failed) || len(failed) > 0 && !reflect.DeepEqual(failed, c.failed) {
+			t.Errorf("%s: failed %v, want %v", c.name, failed, c.failed)
+		}
+	}
+}
+
+func TestGrepJsonPath(t *testing.T) {
+	body := `{"status": "up", "count": 3, "ratio": 0.5, "empty": null,
+		"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}],
+		"tags": ["x", "y"], "nested": {"ok": true}}`
+	cases := []struct {
+		path   string
+		values []string
+		err    bool
+	}{
+		{"$.status", []string{"up"}, false},
+		{"$.count", []string{"3"}, false},
+		{"$.ratio", []string{"0.5"}, false},
+		{"$.empty", []string{"null"}, false},
+		{"$.nested", []string{`{"ok":true}`}, false},
+		{"$.nested.ok", []string{"true"}, false},
+		// a single array value is one value, not a list of values
+		{"$.tags", []string{`["x","y"]`}, false},
+		{"$.items[1].name", []string{"b"}, false},
+		{"$.items[*].id", []string{"1", "2"}, false},
+		{"$..name", []string{"a", "b"}, false},
+		{"$.items[?(@.id > 1)].name", []string{"b"}, false},
+		{"$.tags[0,1]", []string{"x", "y"}, false},
+		{"$.tags[0:1]", []string{"x"}, false},
+		// missing keys and indexes are nothing found, not errors
+		{"$.missing", nil, false},
+		{"$.nested.missing", nil, false},
+		{"$.items[5]", nil, false},
+		{"$.items[", nil, true},
+	}
+	for _, c := range cases {
+		values, err := grepJsonPath(c.path, body)
+		if (err != nil) != c.err {
+			t.Errorf("%s: error %v, want error %v", c.path, err, c.err)
+			continue
+		}
+		if len(values) != len(c.values) || len(values) > 0 && !reflect.DeepEqual(values, c.values) {
+			t.Errorf("%s: values %q, want %q", c.path, values, c.values)
+		}
+	}
+	if _, err := grepJsonPath("$.status", "not json"); err == nil || !strings.HasPrefix(err.Error(), "parse json") {
+		t.Errorf("invalid body: %v", err)
+	}
+}
+
+func TestGrepAssert(t *testing.T) {
+	json := `{"status": "up", "count": 3, "items": [{"id": 1}, {"id": 5}]}`
+	xml := `<health><check name="db">ok</check><check name="cache">ok</check><latency>12</latency></health>`
+	html := `<html><body><h1 class="title"> Observer </h1></body></html>`
+	cases := []struct {
+		name        string
+		grep        Grep
+		contentType string
+		body        string
+		passed      bool
+	}{
+		{"exists", Grep{JsonPath: "$.status"}, "", json, true},
+		{"exists missing", Grep{JsonPath: "$.missing"}, "", json, false},
+		{"equal", Grep{JsonPath: "$.status", Value: "up"}, "", json, true},
+		{"equal number", Grep{JsonPath: "$.count", Value: "3.0"}, "", json, true},
+		{"not equal missing", Grep{JsonPath: "$.missing", Operator: OperatorNotEqual, Value: "x"}, "", json, true},
+		{"greater every value", Grep{JsonPath: "$.items[*].id", Operator: OperatorGreater, Value: "0"}, "", json, true},
+		{"greater some value", Grep{JsonPath: "$.items[*].id", Operator: OperatorGreater, Value: "2"}, "", json, false},
+		{"less not a number", Grep{JsonPath: "$.status", Operator: OperatorLess, Value: "2"}, "", json, false},
+		{"contains", Grep{JsonPath: "$.status", Operator: OperatorContains, Value: "u"}, "", json, true},
+		{"regex", Grep{JsonPath: "$.status", Operator: OperatorRegex, Value: "^u.$"}, "", json, true},
+		{"xpath every node", Grep{Xpath: "//check", Value: "ok"}, "application/xml", xml, true},
+		{"xpath attribute", Grep{Xpath: "//check[@name='db']", Value: "ok"}, "application/xml", xml, true},
+		{"xpath number", Grep{Xpath: "count(//check)", Operator: OperatorGreaterEq, Value: "2"}, "application/xml", xml, true},
+		{"xpath value", Grep{Xpath: "//latency", Operator: OperatorLessEq, Value: "10"}, "application/xml", xml, false},
+		{"html trimmed", Grep{Xpath: "//h1[@class='title']", Value: "Observer"}, "text/html; charset=utf-8", html, true},
+		{"invalid xpath", Grep{Xpath: "//["}, "application/xml", xml, false},
+	}
+	for _, c := range cases {
+		assertion := c.grep.assert(c.contentType, c.body)
+		if assertion.Passed != c.passed {
+			t.Errorf("%s: passed %v, want %v: %s", c.name, assertion.Passed, c.passed, assertion)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		actual, operator, expected string
+		passed, err                bool
+	}{
+		{"1", OperatorEqual, "1.0", true, false},
+		{"a", OperatorEqual, "a", true, false},
+		{"a", OperatorNotEqual, "b", true, false},
+		{"10", OperatorGreater, "9", true, false},
+		{"10", OperatorLess, "9", false, false},
+		{"3", OperatorLessEq, "3", true, false},
+		{"3", OperatorGreaterEq, "4", false, false},
+		{"x", OperatorGreater, "1", false, true},
+		{"1", OperatorGreater, "x", false, true},
+		{"abc", OperatorRegex, "(", false, true},
+		{"1", "~", "1", false, true},
+	}
+	for _, c := range cases {
+		passed, err := compare(c.actual, c.operator, c.expected)
+		if passed != c.passed || (err != nil) != c.err {
+			t.Errorf("%s %s %s: %v %v, want %v error %v", c.actual, c.operator, c.expected, passed, err, c.passed, c.err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package pinger
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// tokenExpiryMargin renews cached tokens before the server rejects them
+const tokenExpiryMargin = 30 * time.Second
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// config builds the client TLS config, certificate files are read on every call to pick up renewals
+func (c ClientTls) config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if c.Ca != "" {
+		bundle, err := os.ReadFile(c.Ca)
+		if err != nil {
+			return nil, fmt.Errorf("ca bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("ca bundle %s: no PEM certificates", c.Ca)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// transport returns the transport of the request proxy and TLS options, keep-alives are disabled
+// so every check opens its own connection and reports the connection phases
+func (r Request) transport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	if r.Proxy != nil {
+		if err := r.Proxy.apply(transport); err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+	}
+	if r.Tls != nil {
+		config, err := r.Tls.config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = config
+	}
+	return transport, nil
+}
+
+// authorize sets the Authorization header by the request auth method
+func (d *Data) authorize(ctx context.Context, request *http.Request, auth *Auth) error {
+	switch {
+	case auth == nil:
+		return nil
+	case auth.Basic != nil:
+		request.SetBasicAuth(auth.Basic.User, auth.Basic.Pass)
+	case auth.Bearer != "":
+		request.Header.Set("Authorization", "Bearer "+auth.Bearer)
+	case auth.OAuth2 != nil:
+		token, err := d.oauth2Token(ctx, *auth.OAuth2)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// oauth2Token returns the cached token or fetches a new one by the client credentials grant
+func (d *Data) oauth2Token(ctx context.Context, config OAuth2) (string, error) {
+	key := config.cacheKey()
+	if token, found := d.tokens.Get(key); found {
+		return token.(string), nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(config.Scopes) > 0 {
+		form.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	if config.Audience != "" {
+		form.Set("audience", config.Audience)
+	}
+	if config.CredentialsInBody {
+		form.Set("client_id", config.ClientId)
+		form.Set("client_secret", config.ClientSecret)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if !config.CredentialsInBody {
+		request.SetBasicAuth(url.QueryEscape(config.ClientId), url.QueryEscape(config.ClientSecret))
+	}
+	client := &http.Client{Timeout: d.webTimeout()}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token response %s: %s", response.Status, shorten(string(body)))
+	}
+	token := oauth2Token{}
+	if err = json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token response: empty access_token")
+	}
+	lifetime := d.settings.GetValueSeconds("OBSERVER_PINGER_OAUTH2_TOKEN_SEC", 300)
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin
+	}
+	if lifetime > 0 {
+		d.tokens.Set(key, token.AccessToken, lifetime)
+	}
+	return token.AccessToken, nil
+}
+
+func (o OAuth2) cacheKey() string {
+	return strings.Join([]string{o.TokenUrl, o.ClientId, o.Audience, strings.Join(o.Scopes, " ")}, "\n")
+}
+
+func (c ClientTls) validate(path string, errs *ValidationErrors) {
+	if (c.Cert == "") != (c.Key == "") {
+		errs.add(path, "cert and key are required together")
+		return
+	}
+	if _, err := c.config(); err != nil {
+		errs.add(path, "%s", err)
+	}
+}
+
+func (a Auth) validate(path string, errs *ValidationErrors) {
+	methods := 0
+	for _, configured := range []bool{a.Basic != nil, a.Bearer != "", a.OAuth2 != nil} {
+		if configured {
+			methods++
+		}
+	}
+	if methods != 1 {
+		errs.add(path, "exactly one of basic, bearer or oauth2 required")
+	}
+	if a.Basic != nil && a.Basic.User == "" {
+		errs.add(path+".basic.user", "required")
+	}
+	if a.OAuth2 != nil {
+		if a.OAuth2.TokenUrl == "" {
+			errs.add(path+".oauth2.token_url", "required")
+		} else {
+			validateUrl(path+".oauth2.token_url", a.OAuth2.TokenUrl, errs)
+		}
+		if a.OAuth2.ClientId == "" {
+			errs.add(path+".oauth2.client_id", "required")
+		}
+	}
+}
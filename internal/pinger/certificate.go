@@ -0,0 +1,125 @@
+package pinger
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+const tlsPort = "443"
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// certificate dials the TLS endpoint, validates the chain and the hostname
+// and checks days until the leaf certificate expiry
+func (d *Data) certificate(ctx context.Context, request Request) ResponseResult {
+	result := ResponseResult{}
+	check := request.Certificate
+	address := check.address()
+	serverName := check.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: request.Timeout},
+		Config: &tls.Config{
+			ServerName: serverName,
+			// the chain is verified below to report certificate details of invalid chains too
+			InsecureSkipVerify: true,
+		},
+	}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return result.WithErr("tls dial err: %s", err)
+	}
+	defer conn.Close()
+	result.Latency = time.Since(start)
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return result.SetErr("no peer certificates")
+	}
+	leaf := state.PeerCertificates[0]
+	now := time.Now()
+	info := &CertificateResult{
+		Subject:  leaf.Subject.String(),
+		Issuer:   leaf.Issuer.String(),
+		SANs:     append(append([]string{}, leaf.DNSNames...), ipStrings(leaf.IPAddresses)...),
+		NotAfter: leaf.NotAfter,
+		DaysLeft: int(math.Floor(leaf.NotAfter.Sub(now).Hours() / 24)),
+		Version:  tlsVersionName(state.Version),
+	}
+	result.Certificate = info
+	result.Body = fmt.Sprintf("%s, issuer %s, expires %s", info.Subject, info.Issuer, info.NotAfter.Format(time.RFC3339))
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, verifyErr := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	info.Verified = verifyErr == nil
+	result.Assertions = []AssertionResult{
+		{
+			Name:     "certificate.chain",
+			Expected: "valid for " + serverName,
+			Actual:   defaultsErr(verifyErr, "valid"),
+			Passed:   verifyErr == nil,
+		},
+		{
+			Name:     "certificate.expiry",
+			Expected: "> " + strconv.Itoa(check.CriticalDays) + " days",
+			Actual:   strconv.Itoa(info.DaysLeft) + " days",
+			Passed:   info.DaysLeft > check.CriticalDays,
+		},
+	}
+	if failed := failedAssertions(result.Assertions); failed != "" {
+		return result.SetErr(failed)
+	}
+	if info.DaysLeft <= check.WarningDays {
+		result.Warning = fmt.Sprintf("certificate expires in %d days, %s", info.DaysLeft, info.NotAfter.Format(time.RFC3339))
+	}
+	result.Successful = true
+	return result
+}
+
+func (c Certificate) address() string {
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return net.JoinHostPort(c.Address, tlsPort)
+	}
+	return c.Address
+}
+
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersions[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func ipStrings(ips []net.IP) []string {
+	result := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		result = append(result, ip.String())
+	}
+	return result
+}
+
+func defaultsErr(err error, defaultVal string) string {
+	if err == nil {
+		return defaultVal
+	}
+	return err.Error()
+}
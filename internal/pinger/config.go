@@ -0,0 +1,460 @@
+package pinger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/dns/dnsmessage"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the monitored items, it is loaded from a YAML or JSON file
+type Config struct {
+	ItemsGroup []ItemsGroup `json:"items_group" yaml:"items_group"`
+}
+
+// ValidationError points at the config value that failed validation
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors collects all problems found in a config
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, item := range e {
+		lines = append(lines, item.Error())
+	}
+	return fmt.Sprintf("invalid config:\n\t%s", strings.Join(lines, "\n\t"))
+}
+
+func (e *ValidationErrors) add(path, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// LoadConfig reads and validates the config file, JSON is accepted as a subset of YAML
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config %s: %w", path, err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig decodes and validates config data
+func ParseConfig(data []byte) (Config, error) {
+	config := Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate checks every group and item, all found problems are returned at once
+func (c Config) Validate() error {
+	errs := ValidationErrors{}
+	keys := make(map[string]string)
+	for groupIndex, group := range c.ItemsGroup {
+		groupPath := fmt.Sprintf("items_group[%d]", groupIndex)
+		if group.Timeout < 0 {
+			errs.add(groupPath+".timeout", "must not be negative")
+		}
+		if group.Schedule != nil {
+			group.Schedule.validate(groupPath+".schedule", &errs)
+		}
+		if group.RequestTimeout < 0 {
+			errs.add(groupPath+".request_timeout", "must not be negative")
+		}
+		if group.Proxy != nil {
+			group.Proxy.validate(groupPath+".proxy", &errs)
+		}
+		if len(group.Items) == 0 {
+			errs.add(groupPath+".items", "must not be empty")
+		}
+		for itemIndex, item := range group.Items {
+			itemPath := fmt.Sprintf("%s.items[%d]", groupPath, itemIndex)
+			key := item.Key()
+			if key == "" {
+				errs.add(itemPath, "id, name or request target required")
+			} else if previous, ok := keys[key]; ok {
+				errs.add(itemPath, "duplicate item key %q, already used by %s", key, previous)
+			} else {
+				keys[key] = itemPath
+			}
+			if item.Schedule != nil {
+				item.Schedule.validate(itemPath+".schedule", &errs)
+			}
+			if schedule := group.scheduleOf(item); schedule.Interval <= 0 && schedule.Cron == "" {
+				errs.add(itemPath, "schedule interval, cron or group timeout required")
+			}
+			if item.KeepCookies && item.Request.Url == "" && len(item.Steps) == 0 {
+				errs.add(itemPath+".keep_cookies", "only web and steps checks support cookies")
+			}
+			if len(item.Steps) > 0 {
+				item.validateSteps(itemPath, &errs)
+				continue
+			}
+			item.Request.validate(itemPath+".request", &errs)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (i Item) validateSteps(path string, errs *ValidationErrors) {
+	if i.Request.Target() != "" {
+		errs.add(path+".request", "request and steps are mutually exclusive")
+	}
+	// retry and trigger of the request apply to the whole steps check
+	if i.Request.Retry != nil {
+		i.Request.Retry.validate(path+".request.retry", errs)
+	}
+	if i.Request.Trigger != nil {
+		i.Request.Trigger.validate(path+".request.trigger", errs)
+	}
+	for stepIndex, step := range i.Steps {
+		stepPath := fmt.Sprintf("%s.steps[%d]", path, stepIndex)
+		if step.Request.Url == "" {
+			errs.add(stepPath+".request.url", "required")
+		} else if _, err := parseTemplate(step.Request.Url); err != nil {
+			errs.add(stepPath+".request.url", "%s", err)
+		} else if !strings.Contains(step.Request.Url, "{{") {
+			validateUrl(stepPath+".request.url", step.Request.Url, errs)
+		}
+		if _, err := parseTemplate(step.Request.Body); err != nil {
+			errs.add(stepPath+".request.body", "%s", err)
+		}
+		if len(step.Request.Proxies) > 0 {
+			errs.add(stepPath+".request.proxies", "not supported in steps, use proxy")
+		}
+		step.Request.validateProxies(stepPath+".request", errs)
+		step.Request.validateClient(stepPath+".request", errs)
+		step.Request.Response.validate(stepPath+".request.response", errs)
+		for extractIndex, extract := range step.Extract {
+			extract.validate(fmt.Sprintf("%s.extract[%d]", stepPath, extractIndex), errs)
+		}
+	}
+}
+
+func (e Extract) validate(path string, errs *ValidationErrors) {
+	if e.Name == "" {
+		errs.add(path+".name", "required")
+	}
+	sources := 0
+	for _, source := range []string{e.JsonPath, e.Regex, e.Header, e.Cookie} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		errs.add(path, "exactly one of json_path, regex, header or cookie required")
+	}
+	if e.JsonPath != "" {
+		if _, err := compileJsonPath(e.JsonPath); err != nil {
+			errs.add(path+".json_path", "%s", err)
+		}
+	}
+	if e.Regex != "" {
+		if _, err := regexp.Compile(e.Regex); err != nil {
+			errs.add(path+".regex", "%s", err)
+		}
+	}
+}
+
+func (r Request) validate(path string, errs *ValidationErrors) {
+	targets := 0
+	for _, configured := range []bool{r.Url != "", r.Ping != "", r.Tcp != nil, r.Dns != nil, r.Certificate != nil, r.Grpc != nil} {
+		if configured {
+			targets++
+		}
+	}
+	switch {
+	case targets == 0:
+		errs.add(path, "url, address, tcp, dns, certificate or grpc required")
+	case targets > 1:
+		errs.add(path, "url, address, tcp, dns, certificate and grpc are mutually exclusive")
+	case r.Url != "":
+		validateUrl(path+".url", r.Url, errs)
+	case r.Tcp != nil:
+		r.Tcp.validate(path+".tcp", errs)
+	case r.Dns != nil:
+		r.Dns.validate(path+".dns", errs)
+	case r.Certificate != nil:
+		r.Certificate.validate(path+".certificate", errs)
+	case r.Grpc != nil:
+		if _, _, err := net.SplitHostPort(r.Grpc.Address); err != nil {
+			errs.add(path+".grpc.address", "host:port required, %s", err)
+		}
+	}
+	if r.Repeat < 0 {
+		errs.add(path+".repeat", "must not be negative")
+	}
+	if r.Timeout < 0 {
+		errs.add(path+".timeout", "must not be negative")
+	}
+	r.validateProxies(path, errs)
+	r.validateClient(path, errs)
+	r.Response.validate(path+".response", errs)
+	if r.Retry != nil {
+		r.Retry.validate(path+".retry", errs)
+	}
+	if r.Trigger != nil {
+		r.Trigger.validate(path+".trigger", errs)
+	}
+}
+
+func (r Request) validateProxies(path string, errs *ValidationErrors) {
+	if r.Proxy == nil && len(r.Proxies) == 0 {
+		return
+	}
+	if r.Url == "" {
+		errs.add(path+".proxy", "only web checks support proxies")
+		return
+	}
+	if r.Proxy != nil && len(r.Proxies) > 0 {
+		errs.add(path+".proxies", "proxy and proxies are mutually exclusive")
+	}
+	if r.Proxy != nil {
+		r.Proxy.validate(path+".proxy", errs)
+	}
+	labels := make(map[string]bool)
+	for proxyIndex, proxy := range r.Proxies {
+		proxyPath := fmt.Sprintf("%s.proxies[%d]", path, proxyIndex)
+		proxy.validate(proxyPath, errs)
+		if labels[proxy.label()] {
+			errs.add(proxyPath+".name", "duplicate proxy %q", proxy.label())
+		}
+		labels[proxy.label()] = true
+	}
+}
+
+func (r Request) validateClient(path string, errs *ValidationErrors) {
+	if (r.Tls != nil || r.Auth != nil || r.Redirect != nil) && r.Url == "" {
+		errs.add(path, "only web checks support tls, auth and redirect")
+		return
+	}
+	if r.Redirect != nil {
+		r.Redirect.validate(path+".redirect", errs)
+	}
+	if r.Tls != nil {
+		r.Tls.validate(path+".tls", errs)
+	}
+	if r.Auth != nil {
+		r.Auth.validate(path+".auth", errs)
+	}
+}
+
+func (r Response) validate(path string, errs *ValidationErrors) {
+	status := r.Status
+	if status.Code != 0 && (status.Code < 100 || status.Code > 599) {
+		errs.add(path+".status.code", "invalid http status %d", status.Code)
+	}
+	if status.Min != 0 && status.Max != 0 && status.Min > status.Max {
+		errs.add(path+".status", "min %d is greater than max %d", status.Min, status.Max)
+	}
+	for i, code := range status.List {
+		if code < 100 || code > 599 {
+			errs.add(fmt.Sprintf("%s.status.list[%d]", path, i), "invalid http status %d", code)
+		}
+	}
+	if _, err := regexp.Compile(r.FinalUrl); err != nil {
+		errs.add(path+".final_url", "%s", err)
+	}
+	if _, err := regexp.Compile(r.Location); err != nil {
+		errs.add(path+".location", "%s", err)
+	}
+	if r.Body != nil && r.Body.Regex != "" {
+		if _, err := regexp.Compile(r.Body.Regex); err != nil {
+			errs.add(path+".body.regex", "%s", err)
+		}
+	}
+	if r.Body != nil && r.Body.Grep != nil {
+		r.Body.Grep.validate(path+".body.grep", errs)
+	}
+	if limits := r.Ping; limits != nil {
+		failLoss := 100.0
+		if limits.FailLoss != nil {
+			failLoss = *limits.FailLoss
+			if failLoss < 0 || failLoss >= 100 {
+				errs.add(path+".ping.fail_loss", "must be within [0, 100)")
+			}
+		}
+		switch {
+		case limits.DegradedLoss < 0 || limits.DegradedLoss >= 100:
+			errs.add(path+".ping.degraded_loss", "must be within [0, 100)")
+		case limits.DegradedLoss > failLoss:
+			errs.add(path+".ping.degraded_loss", "must be within [0, fail_loss]")
+		}
+		if limits.DegradedAvg < 0 || limits.FailAvg < 0 {
+			errs.add(path+".ping", "degraded_avg and fail_avg must not be negative")
+		}
+	}
+	if timing := r.Timing; timing != nil {
+		for _, limit := range []time.Duration{timing.DnsLookup, timing.TcpConnect, timing.TlsHandshake,
+			timing.FirstByte, timing.ContentTransfer, timing.Total} {
+			if limit < 0 {
+				errs.add(path+".timing", "limits must not be negative")
+				break
+			}
+		}
+	}
+}
+
+func (g Grep) validate(path string, errs *ValidationErrors) {
+	switch {
+	case g.Xpath == "" && g.JsonPath == "":
+		errs.add(path, "xpath or json_path required")
+	case g.Xpath != "" && g.JsonPath != "":
+		errs.add(path, "xpath and json_path are mutually exclusive")
+	case g.Xpath != "":
+		if _, err := xpath.Compile(g.Xpath); err != nil {
+			errs.add(path+".xpath", "%s", err)
+		}
+	default:
+		if _, err := compileJsonPath(g.JsonPath); err != nil {
+			errs.add(path+".json_path", "%s", err)
+		}
+	}
+	if !slices.Contains(grepOperators, g.operator()) {
+		errs.add(path+".operator", "unknown operator %q, expected one of %v", g.Operator, grepOperators)
+	}
+	if g.operator() == OperatorRegex {
+		if _, err := regexp.Compile(g.Value); err != nil {
+			errs.add(path+".value", "%s", err)
+		}
+	}
+}
+
+func (r Retry) validate(path string, errs *ValidationErrors) {
+	if r.Attempts < 1 {
+		errs.add(path+".attempts", "must be positive")
+	}
+	if r.Backoff < 0 || r.MaxBackoff < 0 {
+		errs.add(path, "backoff and max_backoff must not be negative")
+	}
+	if r.Multiplier < 0 {
+		errs.add(path+".multiplier", "must not be negative")
+	}
+	for i, kind := range r.On {
+		if !slices.Contains(retryErrors, kind) {
+			errs.add(fmt.Sprintf("%s.on[%d]", path, i), "unknown error kind %q, expected one of %v", kind, retryErrors)
+		}
+	}
+}
+
+func (t Trigger) validate(path string, errs *ValidationErrors) {
+	if t.Antispam != nil && *t.Antispam < 0 {
+		errs.add(path+".antispam", "must not be negative")
+	}
+	if t.SkipBy < 0 {
+		errs.add(path+".skip_by", "must not be negative")
+	}
+	names := []string{"on_successful", "on_fail", "always"}
+	for i, request := range []*Request{t.OnSuccessful, t.OnFail, t.Always} {
+		if request == nil {
+			continue
+		}
+		requestPath := path + "." + names[i]
+		if request.Url == "" {
+			errs.add(requestPath+".url", "required")
+		}
+		validateTemplate(requestPath+".url", request.Url, TriggerData{}, errs)
+		validateTemplate(requestPath+".body", request.Body, TriggerData{}, errs)
+		for key, values := range request.Header {
+			for _, value := range values {
+				validateTemplate(requestPath+".header."+key, value, TriggerData{}, errs)
+			}
+		}
+	}
+}
+
+func (t Tcp) validate(path string, errs *ValidationErrors) {
+	if _, _, err := net.SplitHostPort(t.Address); err != nil {
+		errs.add(path+".address", "host:port required, %s", err)
+	}
+}
+
+func (c Dns) validate(path string, errs *ValidationErrors) {
+	if c.Name == "" {
+		errs.add(path+".name", "required")
+	} else if _, err := dnsmessage.NewName(dnsFqdn(c.Name)); err != nil {
+		errs.add(path+".name", "%s", err)
+	}
+	if _, ok := dnsTypes[strings.ToUpper(c.Type)]; c.Type != "" && !ok {
+		errs.add(path+".type", "unsupported record type %q", c.Type)
+	}
+	if c.MinTtl < 0 || c.MaxTtl < 0 || c.MaxTime < 0 {
+		errs.add(path, "min_ttl, max_ttl and max_time must not be negative")
+	}
+	if c.MaxTtl > 0 && c.MinTtl > c.MaxTtl {
+		errs.add(path+".min_ttl", "%s is greater than max_ttl %s", c.MinTtl, c.MaxTtl)
+	}
+}
+
+func (c Certificate) validate(path string, errs *ValidationErrors) {
+	if c.Address == "" {
+		errs.add(path+".address", "required")
+	} else if err := validateHostPort(c.address()); err != nil || strings.ContainsAny(c.Address, "/?#") {
+		errs.add(path+".address", "host or host:port required, got %q", c.Address)
+	}
+	if c.WarningDays < 0 || c.CriticalDays < 0 {
+		errs.add(path, "warning_days and critical_days must not be negative")
+	}
+	if c.WarningDays > 0 && c.CriticalDays > c.WarningDays {
+		errs.add(path+".critical_days", "%d is greater than warning_days %d", c.CriticalDays, c.WarningDays)
+	}
+}
+
+// validateHostPort checks the address is host:port with a non-empty host and a numeric port
+func validateHostPort(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("host is empty")
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+func validateUrl(path, address string, errs *ValidationErrors) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		errs.add(path, "%s", err)
+		return
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		errs.add(path, "absolute url with scheme and host required, got %q", address)
+	}
+}
@@ -0,0 +1,133 @@
+package pinger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConfigErrors parses the group items and returns paths of validation errors
+func testConfigErrors(t *testing.T, items string) []string {
+	t.Helper()
+	data := "items_group:\n  - timeout: 1m\n    items:\n" + items
+	_, err := ParseConfig([]byte(data))
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("parse: %v", err)
+	}
+	paths := make([]string, 0, len(errs))
+	for _, item := range errs {
+		paths = append(paths, item.Path)
+	}
+	return paths
+}
+
+func TestConfigPingLimits(t *testing.T) {
+	cases := []struct {
+		name   string
+		limits string
+		errors []string
+	}{
+		{"degraded avg only", "{degraded_avg: 200ms}", nil},
+		{"degraded loss only", "{degraded_loss: 10}", nil},
+		{"explicit zero fail loss", "{fail_loss: 0}", nil},
+		{"degraded above fail", "{fail_loss: 5, degraded_loss: 10}", []string{"ping.degraded_loss"}},
+		{"degraded out of range", "{degraded_loss: 100}", []string{"ping.degraded_loss"}},
+		{"fail out of range", "{fail_loss: 100}", []string{"ping.fail_loss"}},
+		{"negative avg", "{fail_avg: -1s}", []string{"ping"}},
+	}
+	for _, c := range cases {
+		items := "      - name: ping\n        request: {address: 127.0.0.1, response: {ping: " + c.limits + "}}\n"
+		paths := testConfigErrors(t, items)
+		if len(paths) != len(c.errors) {
+			t.Errorf("%s: errors %v, want %v", c.name, paths, c.errors)
+			continue
+		}
+		for i, path := range paths {
+			if !strings.HasSuffix(path, ".response."+c.errors[i]) {
+				t.Errorf("%s: error %s, want %s", c.name, path, c.errors[i])
+			}
+		}
+	}
+}
+
+func TestPingLimitsFailLossDefault(t *testing.T) {
+	stats := &PingResult{Sent: 10, Received: 9, Loss: 10, Avg: 100 * time.Millisecond}
+	zero := 0.0
+	cases := []struct {
+		name             string
+		limits           PingLimits
+		failed, degraded bool
+	}{
+		// any loss is degraded unless degraded_loss is set
+		{"omitted", PingLimits{DegradedAvg: 200 * time.Millisecond}, false, true},
+		{"omitted with degraded loss", PingLimits{DegradedLoss: 5}, false, true},
+		{"explicit zero", PingLimits{FailLoss: &zero}, true, true},
+	}
+	for _, c := range cases {
+		limits := c.limits.withFailLoss(50)
+		failed := failedAssertions(limits.assert(stats, false)) != ""
+		degraded := failedAssertions(limits.assert(stats, true)) != ""
+		if failed != c.failed || degraded != c.degraded {
+			t.Errorf("%s: failed %v degraded %v, want %v %v", c.name, failed, degraded, c.failed, c.degraded)
+		}
+	}
+}
+
+func TestConfigStepsRetryAndTrigger(t *testing.T) {
+	cases := []struct {
+		name    string
+		request string
+		errors  []string
+	}{
+		{"valid", "{retry: {attempts: 2}, trigger: {on_fail: {url: 'http://hook/{{.Key}}'}}}", nil},
+		{"retry attempts", "{retry: {attempts: 0}}", []string{"request.retry.attempts"}},
+		{"retry error kind", "{retry: {attempts: 1, on: [oops]}}", []string{"request.retry.on[0]"}},
+		{"trigger template", "{trigger: {on_fail: {url: 'http://hook/{{.Key'}}}", []string{"request.trigger.on_fail.url"}},
+		{"trigger skip_by", "{trigger: {skip_by: -1}}", []string{"request.trigger.skip_by"}},
+	}
+	for _, c := range cases {
+		items := "      - name: steps\n        request: " + c.request + "\n        steps:\n          - request: {url: 'http://localhost/'}\n"
+		paths := testConfigErrors(t, items)
+		if len(paths) != len(c.errors) {
+			t.Errorf("%s: errors %v, want %v", c.name, paths, c.errors)
+			continue
+		}
+		for i, path := range paths {
+			if path != "items_group[0].items[0]."+c.errors[i] {
+				t.Errorf("%s: error %s, want %s", c.name, path, c.errors[i])
+			}
+		}
+	}
+}
+
+func TestConfigCertificateAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		valid   bool
+	}{
+		{"example.com", true},
+		{"example.com:8443", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"[::1]:443", true},
+		{"https://example.com", false},
+		{"example.com/path", false},
+		{"example.com:https", false},
+		{"example.com:70000", false},
+		{":443", false},
+	}
+	for _, c := range cases {
+		items := "      - name: cert\n        request: {certificate: {address: '" + c.address + "'}}\n"
+		paths := testConfigErrors(t, items)
+		if c.valid && len(paths) != 0 {
+			t.Errorf("%s: errors %v, want none", c.address, paths)
+		}
+		if !c.valid && (len(paths) != 1 || paths[0] != "items_group[0].items[0].request.certificate.address") {
+			t.Errorf("%s: errors %v, want the address error", c.address, paths)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package pinger
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsPort           = "53"
+	dnsResolvConf     = "/etc/resolv.conf"
+	dnsDefaultServer  = "127.0.0.1"
+	dnsMaxMessageSize = 65535
+)
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"TXT":   dnsmessage.TypeTXT,
+	"SRV":   dnsmessage.TypeSRV,
+}
+
+type dnsAnswer struct {
+	value string
+	ttl   time.Duration
+}
+
+// dns resolves the name and checks the answers, TTL bounds and response time
+func (d *Data) dns(ctx context.Context, request Request) ResponseResult {
+	result := ResponseResult{}
+	check := request.Dns
+	if request.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, request.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	answers, err := resolve(ctx, check.resolver(), check.Name, check.dnsType())
+	result.Latency = time.Since(start)
+	if err != nil {
+		return result.WithErr("resolve err: %s", err)
+	}
+	values := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		values = append(values, answer.value)
+	}
+	result.Body = strings.Join(values, "\n")
+	result.Assertions = check.assert(answers, result.Latency)
+	if failed := failedAssertions(result.Assertions); failed != "" {
+		return result.SetErr(failed)
+	}
+	result.Successful = true
+	return result
+}
+
+func (c Dns) assert(answers []dnsAnswer, latency time.Duration) []AssertionResult {
+	values := make([]string, 0, len(answers))
+	for _, answer := range answers {
+		values = append(values, answer.value)
+	}
+	actual := fmt.Sprintf("%v", values)
+	assertions := []AssertionResult{{
+		Name:     "dns.answers",
+		Expected: "at least one " + c.dnsType().String() + " record",
+		Actual:   actual,
+		Passed:   len(answers) > 0,
+	}}
+	for _, expected := range c.Expect {
+		assertion := AssertionResult{
+			Name:     "dns.expect",
+			Expected: expected,
+			Actual:   actual,
+		}
+		for _, answer := range answers {
+			if dnsValueMatch(answer.value, expected) {
+				assertion.Passed = true
+			}
+		}
+		assertions = append(assertions, assertion)
+	}
+	if c.MinTtl > 0 || c.MaxTtl > 0 {
+		for _, answer := range answers {
+			assertions = append(assertions, AssertionResult{
+				Name:     "dns.ttl",
+				Expected: fmt.Sprintf("[%s, %s]", c.MinTtl, c.MaxTtl),
+				Actual:   fmt.Sprintf("%s for %s", answer.ttl, answer.value),
+				Passed:   answer.ttl >= c.MinTtl && (c.MaxTtl == 0 || answer.ttl <= c.MaxTtl),
+			})
+		}
+	}
+	if c.MaxTime > 0 {
+		assertions = append(assertions, AssertionResult{
+			Name:     "dns.time",
+			Expected: "<= " + c.MaxTime.String(),
+			Actual:   latency.String(),
+			Passed:   latency <= c.MaxTime,
+		})
+	}
+	return assertions
+}
+
+// dnsValueMatch compares names case and trailing dot insensitive,
+// the expected value may be the last field only, e.g. MX host without preference
+func dnsValueMatch(value, expected string) bool {
+	normalize := func(v string) string {
+		return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(v)), ".")
+	}
+	value, expected = normalize(value), normalize(expected)
+	if value == expected {
+		return true
+	}
+	fields := strings.Fields(value)
+	return len(fields) > 1 && fields[len(fields)-1] == expected
+}
+
+func (c Dns) dnsType() dnsmessage.Type {
+	if dnsType, ok := dnsTypes[strings.ToUpper(c.Type)]; ok {
+		return dnsType
+	}
+	return dnsmessage.TypeA
+}
+
+// resolver returns the configured server or the first nameserver of the system config
+func (c Dns) resolver() string {
+	server := c.Resolver
+	if server == "" {
+		server = systemNameserver()
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, dnsPort)
+	}
+	return server
+}
+
+func systemNameserver() string {
+	file, err := os.Open(dnsResolvConf)
+	if err != nil {
+		return dnsDefaultServer
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "nameserver" {
+			return fields[1]
+		}
+	}
+	return dnsDefaultServer
+}
+
+// resolve queries the server over UDP and repeats over TCP when the answer is truncated
+func resolve(ctx context.Context, server, name string, dnsType dnsmessage.Type) ([]dnsAnswer, error) {
+	fqdn, err := dnsmessage.NewName(dnsFqdn(name))
+	if err != nil {
+		return nil, err
+	}
+	id := uint16(rand.Uint32())
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: dnsType, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	response, err := exchange(ctx, "udp", server, packed)
+	if err == nil && response.Truncated {
+		response, err = exchange(ctx, "tcp", server, packed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.ID != id {
+		return nil, fmt.Errorf("response id %d does not match query id %d", response.ID, id)
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("response code %s", response.RCode)
+	}
+	answers := make([]dnsAnswer, 0, len(response.Answers))
+	for _, resource := range response.Answers {
+		if resource.Header.Type != dnsType {
+			continue
+		}
+		answers = append(answers, dnsAnswer{
+			value: dnsValue(resource.Body),
+			ttl:   time.Duration(resource.Header.TTL) * time.Second,
+		})
+	}
+	return answers, nil
+}
+
+func exchange(ctx context.Context, network, server string, query []byte) (*dnsmessage.Message, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	var data []byte
+	if network == "tcp" {
+		data, err = exchangeStream(conn, query)
+	} else {
+		data, err = exchangePacket(conn, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := &dnsmessage.Message{}
+	if err = response.Unpack(data); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func exchangePacket(conn net.Conn, query []byte) ([]byte, error) {
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, dnsMaxMessageSize)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer[:n], nil
+}
+
+// exchangeStream sends and receives messages prefixed by the two bytes length
+func exchangeStream(conn net.Conn, query []byte) ([]byte, error) {
+	message := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(message, uint16(len(query)))
+	copy(message[2:], query)
+	if _, err := conn.Write(message); err != nil {
+		return nil, err
+	}
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func dnsValue(body dnsmessage.ResourceBody) string {
+	switch body := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(body.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(body.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return body.CNAME.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", body.Pref, body.MX.String())
+	case *dnsmessage.TXTResource:
+		return strings.Join(body.TXT, "")
+	case *dnsmessage.SRVResource:
+		return strings.Join([]string{
+			strconv.Itoa(int(body.Priority)),
+			strconv.Itoa(int(body.Weight)),
+			strconv.Itoa(int(body.Port)),
+			body.Target.String(),
+		}, " ")
+	}
+	return body.GoString()
+}
+
+func dnsFqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
@@ -0,0 +1,255 @@
+package pinger
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testDnsServer answers queries over UDP and TCP on the same local port
+type testDnsServer struct {
+	address  string
+	records  map[dnsmessage.Type][]dnsmessage.Resource
+	truncate bool
+	wrongId  bool
+	rcode    dnsmessage.RCode
+	mutex    sync.Mutex
+	queries  map[string]int
+}
+
+func newTestDnsServer(t *testing.T) *testDnsServer {
+	t.Helper()
+	server := &testDnsServer{records: make(map[dnsmessage.Type][]dnsmessage.Resource), queries: make(map[string]int)}
+	var packet net.PacketConn
+	var listener net.Listener
+	for attempt := 0; listener == nil; attempt++ {
+		var err error
+		if packet, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		listener, err = net.Listen("tcp", packet.LocalAddr().String())
+		if err != nil {
+			packet.Close()
+			if attempt > 10 {
+				t.Fatal(err)
+			}
+		}
+	}
+	t.Cleanup(func() {
+		packet.Close()
+		listener.Close()
+	})
+	server.address = packet.LocalAddr().String()
+	go server.servePackets(packet)
+	go server.serveStreams(listener)
+	return server
+}
+
+func (s *testDnsServer) add(name string, ttl uint32, body dnsmessage.ResourceBody) {
+	s.update(func() {
+		header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: bodyType(body), Class: dnsmessage.ClassINET, TTL: ttl}
+		s.records[header.Type] = append(s.records[header.Type], dnsmessage.Resource{Header: header, Body: body})
+	})
+}
+
+// update changes the server behaviour between queries
+func (s *testDnsServer) update(change func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	change()
+}
+
+func bodyType(body dnsmessage.ResourceBody) dnsmessage.Type {
+	switch body.(type) {
+	case *dnsmessage.AResource:
+		return dnsmessage.TypeA
+	case *dnsmessage.AAAAResource:
+		return dnsmessage.TypeAAAA
+	case *dnsmessage.MXResource:
+		return dnsmessage.TypeMX
+	case *dnsmessage.SRVResource:
+		return dnsmessage.TypeSRV
+	case *dnsmessage.TXTResource:
+		return dnsmessage.TypeTXT
+	case *dnsmessage.CNAMEResource:
+		return dnsmessage.TypeCNAME
+	}
+	return 0
+}
+
+func (s *testDnsServer) answer(network string, data []byte) []byte {
+	query := dnsmessage.Message{}
+	if err := query.Unpack(data); err != nil || len(query.Questions) == 0 {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queries[network]++
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: s.rcode},
+		Questions: query.Questions,
+	}
+	if s.wrongId {
+		response.ID++
+	}
+	if network == "udp" && s.truncate {
+		response.Truncated = true
+	} else {
+		response.Answers = s.records[query.Questions[0].Type]
+	}
+	packed, err := response.Pack()
+	if err != nil {
+		return nil
+	}
+	return packed
+}
+
+func (s *testDnsServer) servePackets(conn net.PacketConn) {
+	buffer := make([]byte, dnsMaxMessageSize)
+	for {
+		n, address, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		if response := s.answer("udp", buffer[:n]); response != nil {
+			_, _ = conn.WriteTo(response, address)
+		}
+	}
+}
+
+func (s *testDnsServer) serveStreams(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			length := make([]byte, 2)
+			if _, err := io.ReadFull(conn, length); err != nil {
+				return
+			}
+			data := make([]byte, binary.BigEndian.Uint16(length))
+			if _, err := io.ReadFull(conn, data); err != nil {
+				return
+			}
+			response := s.answer("tcp", data)
+			message := make([]byte, 2+len(response))
+			binary.BigEndian.PutUint16(message, uint16(len(response)))
+			copy(message[2:], response)
+			_, _ = conn.Write(message)
+		}()
+	}
+}
+
+func (s *testDnsServer) count(network string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.queries[network]
+}
+
+func testDnsCheck(server *testDnsServer, check Dns) ResponseResult {
+	check.Resolver = server.address
+	d := &Data{}
+	return d.dns(context.Background(), Request{Dns: &check, Timeout: 2 * time.Second})
+}
+
+func TestDnsCheck(t *testing.T) {
+	server := newTestDnsServer(t)
+	server.add("example.test.", 300, &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})
+	server.add("example.test.", 300, &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}})
+	server.add("example.test.", 60, &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("Mail.Example.Test.")})
+	server.add("_http._tcp.example.test.", 60, &dnsmessage.SRVResource{
+		Priority: 1, Weight: 5, Port: 8080, Target: dnsmessage.MustNewName("web.example.test."),
+	})
+
+	cases := []struct {
+		name       string
+		check      Dns
+		successful bool
+		body       string
+	}{
+		{"a records", Dns{Name: "example.test", Expect: []string{"10.0.0.2"}}, true, "10.0.0.1\n10.0.0.2"},
+		{"missing a record", Dns{Name: "example.test", Expect: []string{"10.0.0.3"}}, false, ""},
+		{"mx by host", Dns{Name: "example.test", Type: "mx", Expect: []string{"mail.example.test"}}, true, "10 Mail.Example.Test."},
+		{"mx by full value", Dns{Name: "example.test", Type: "MX", Expect: []string{"10 MAIL.example.test."}}, true, ""},
+		{"srv by target", Dns{Name: "_http._tcp.example.test", Type: "SRV", Expect: []string{"web.example.test."}}, true, "1 5 8080 web.example.test."},
+		{"srv by full value", Dns{Name: "_http._tcp.example.test", Type: "SRV", Expect: []string{"1 5 8080 web.example.test"}}, true, ""},
+		{"no records", Dns{Name: "example.test", Type: "AAAA"}, false, ""},
+		{"ttl within bounds", Dns{Name: "example.test", MinTtl: time.Minute, MaxTtl: 10 * time.Minute}, true, ""},
+		{"ttl below min", Dns{Name: "example.test", Type: "MX", MinTtl: 2 * time.Minute}, false, ""},
+		{"ttl above max", Dns{Name: "example.test", MaxTtl: time.Minute}, false, ""},
+		{"max time", Dns{Name: "example.test", MaxTime: time.Minute}, true, ""},
+	}
+	for _, c := range cases {
+		result := testDnsCheck(server, c.check)
+		if result.Successful != c.successful {
+			t.Errorf("%s: successful %v, want %v: %s", c.name, result.Successful, c.successful, result.Error)
+		}
+		if c.body != "" && result.Body != c.body {
+			t.Errorf("%s: body %q, want %q", c.name, result.Body, c.body)
+		}
+	}
+	if server.count("tcp") != 0 {
+		t.Errorf("tcp queries %d without truncation", server.count("tcp"))
+	}
+}
+
+func TestDnsTruncatedFallsBackToTcp(t *testing.T) {
+	server := newTestDnsServer(t)
+	server.update(func() { server.truncate = true })
+	server.add("example.test.", 300, &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})
+	result := testDnsCheck(server, Dns{Name: "example.test", Expect: []string{"10.0.0.1"}})
+	if !result.Successful {
+		t.Fatalf("truncated answer: %s", result.Error)
+	}
+	if server.count("udp") != 1 || server.count("tcp") != 1 {
+		t.Errorf("queries udp %d tcp %d, want one of each", server.count("udp"), server.count("tcp"))
+	}
+}
+
+func TestDnsResponseErrors(t *testing.T) {
+	server := newTestDnsServer(t)
+	server.add("example.test.", 300, &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})
+	server.update(func() { server.wrongId = true })
+	result := testDnsCheck(server, Dns{Name: "example.test"})
+	if result.Successful || !strings.Contains(result.Error, "does not match query id") {
+		t.Errorf("wrong id: %v %q", result.Successful, result.Error)
+	}
+	server.update(func() {
+		server.wrongId = false
+		server.rcode = dnsmessage.RCodeNameError
+	})
+	result = testDnsCheck(server, Dns{Name: "example.test"})
+	if result.Successful || !strings.Contains(result.Error, "response code") {
+		t.Errorf("rcode: %v %q", result.Successful, result.Error)
+	}
+}
+
+func TestDnsValueMatch(t *testing.T) {
+	cases := []struct {
+		value, expected string
+		match           bool
+	}{
+		{"10.0.0.1", "10.0.0.1", true},
+		{"mail.example.test.", "MAIL.example.test", true},
+		{"10 mail.example.test.", "mail.example.test", true},
+		{"10 mail.example.test.", "10 mail.example.test", true},
+		{"10 mail.example.test.", "20 mail.example.test", false},
+		{"1 5 8080 web.example.test.", "web.example.test.", true},
+		{"1 5 8080 web.example.test.", "8080", false},
+		{"mail.example.test.", "example.test", false},
+	}
+	for _, c := range cases {
+		if got := dnsValueMatch(c.value, c.expected); got != c.match {
+			t.Errorf("dnsValueMatch(%q, %q) = %v, want %v", c.value, c.expected, got, c.match)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package pinger
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// grpcHealth calls grpc.health.v1.Health/Check, only SERVING is successful
+func (d *Data) grpcHealth(ctx context.Context, request Request) ResponseResult {
+	result := ResponseResult{}
+	check := request.Grpc
+	if request.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, request.Timeout)
+		defer cancel()
+	}
+	transport := insecure.NewCredentials()
+	if check.Tls {
+		transport = credentials.NewTLS(&tls.Config{
+			ServerName:         check.ServerName,
+			InsecureSkipVerify: check.InsecureSkipVerify,
+		})
+	}
+	conn, err := grpc.DialContext(ctx, check.Address, grpc.WithTransportCredentials(transport))
+	if err != nil {
+		return result.WithErr("grpc dial err: %s", err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: check.Service})
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.StatusCode = int(status.Code(err))
+		result = result.WithErr("grpc health check err: %s", err)
+		switch status.Code(err) {
+		case codes.DeadlineExceeded:
+			result.ErrorKind = ErrorTimeout
+		case codes.Unavailable:
+			result.ErrorKind = ErrorConnection
+		}
+		return result
+	}
+	result.Body = response.GetStatus().String()
+	result.Assertions = []AssertionResult{{
+		Name:     "grpc.status",
+		Expected: healthpb.HealthCheckResponse_SERVING.String(),
+		Actual:   result.Body,
+		Passed:   response.GetStatus() == healthpb.HealthCheckResponse_SERVING,
+	}}
+	if failed := failedAssertions(result.Assertions); failed != "" {
+		return result.SetErr(failed)
+	}
+	result.Successful = true
+	return result
+}
@@ -0,0 +1,126 @@
+package pinger
+
+import (
+	"sync"
+	"time"
+)
+
+// History keeps the latest check results of every item, bounded by count and age
+type History struct {
+	limit  int
+	maxAge time.Duration
+	items  map[string]*historyRing
+	mutex  *sync.RWMutex
+}
+
+type historyRing struct {
+	records []HistoryRecord
+	start   int
+	size    int
+}
+
+func NewHistory(limit int, maxAge time.Duration) *History {
+	if limit < 1 {
+		limit = 1
+	}
+	return &History{
+		limit:  limit,
+		maxAge: maxAge,
+		items:  make(map[string]*historyRing),
+		mutex:  &sync.RWMutex{},
+	}
+}
+
+// Add stores the record, the ring grows up to the limit and then the oldest record is overwritten
+func (h *History) Add(key string, record HistoryRecord) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ring, ok := h.items[key]
+	if !ok {
+		ring = &historyRing{}
+		h.items[key] = ring
+	}
+	ring.push(record, h.limit)
+	ring.expire(h.expiredBefore())
+}
+
+// prune drops the history of items missing in keys
+func (h *History) prune(keys map[string]bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for key := range h.items {
+		if !keys[key] {
+			delete(h.items, key)
+		}
+	}
+}
+
+// Get returns item records in chronological order within [from, to], zero bounds are ignored
+func (h *History) Get(key string, from, to time.Time) []HistoryRecord {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	ring, ok := h.items[key]
+	if !ok {
+		return nil
+	}
+	expired := h.expiredBefore()
+	result := make([]HistoryRecord, 0, ring.size)
+	for i := 0; i < ring.size; i++ {
+		record := ring.records[(ring.start+i)%len(ring.records)]
+		if record.EventDate.Before(expired) ||
+			!from.IsZero() && record.EventDate.Before(from) ||
+			!to.IsZero() && record.EventDate.After(to) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+// Keys returns keys of all items with history
+func (h *History) Keys() []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	keys := make([]string, 0, len(h.items))
+	for key := range h.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (h *History) expiredBefore() time.Time {
+	if h.maxAge <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-h.maxAge)
+}
+
+func (r *historyRing) push(record HistoryRecord, limit int) {
+	if r.size == len(r.records) && len(r.records) < limit {
+		if r.start != 0 {
+			// the ring is full, records are put in order before it grows
+			records := make([]HistoryRecord, 0, len(r.records)+1)
+			records = append(records, r.records[r.start:]...)
+			r.records = append(records, r.records[:r.start]...)
+			r.start = 0
+		}
+		r.records = append(r.records, record)
+		r.size++
+		return
+	}
+	end := (r.start + r.size) % len(r.records)
+	r.records[end] = record
+	if r.size < len(r.records) {
+		r.size++
+		return
+	}
+	r.start = (r.start + 1) % len(r.records)
+}
+
+func (r *historyRing) expire(before time.Time) {
+	for r.size > 0 && r.records[r.start].EventDate.Before(before) {
+		r.records[r.start] = HistoryRecord{}
+		r.start = (r.start + 1) % len(r.records)
+		r.size--
+	}
+}
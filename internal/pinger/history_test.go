@@ -0,0 +1,120 @@
+package pinger
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func historyCodes(records []HistoryRecord) []int {
+	codes := make([]int, 0, len(records))
+	for _, record := range records {
+		codes = append(codes, record.StatusCode)
+	}
+	return codes
+}
+
+func TestHistoryRing(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name  string
+		limit int
+		add   int
+		want  []int
+	}{
+		{"empty", 3, 0, nil},
+		{"partial", 3, 2, []int{1, 2}},
+		{"full", 3, 3, []int{1, 2, 3}},
+		{"wrapped once", 3, 4, []int{2, 3, 4}},
+		{"wrapped twice", 3, 8, []int{6, 7, 8}},
+		{"single record", 1, 5, []int{5}},
+		{"limit below one", 0, 2, []int{2}},
+	}
+	for _, c := range cases {
+		history := NewHistory(c.limit, 0)
+		for i := 1; i <= c.add; i++ {
+			history.Add("item", HistoryRecord{StatusCode: i, EventDate: now.Add(time.Duration(i) * time.Second)})
+		}
+		if got := historyCodes(history.Get("item", time.Time{}, time.Time{})); !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestHistoryBounds(t *testing.T) {
+	now := time.Now()
+	history := NewHistory(10, 0)
+	for i := 1; i <= 5; i++ {
+		history.Add("item", HistoryRecord{StatusCode: i, EventDate: now.Add(time.Duration(i) * time.Minute)})
+	}
+	history.Add("other", HistoryRecord{StatusCode: 100, EventDate: now})
+	cases := []struct {
+		name     string
+		from, to time.Time
+		want     []int
+	}{
+		{"from", now.Add(4 * time.Minute), time.Time{}, []int{4, 5}},
+		{"to", time.Time{}, now.Add(2 * time.Minute), []int{1, 2}},
+		{"within", now.Add(2 * time.Minute), now.Add(3 * time.Minute), []int{2, 3}},
+		{"outside", now.Add(time.Hour), time.Time{}, []int{}},
+	}
+	for _, c := range cases {
+		if got := historyCodes(history.Get("item", c.from, c.to)); !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if got := history.Get("missing", time.Time{}, time.Time{}); got != nil {
+		t.Errorf("missing item: got %v", got)
+	}
+	if keys := history.Keys(); len(keys) != 2 {
+		t.Errorf("keys %v, want item and other", keys)
+	}
+}
+
+func TestHistoryExpiry(t *testing.T) {
+	now := time.Now()
+	history := NewHistory(5, time.Hour)
+	history.Add("item", HistoryRecord{StatusCode: 1, EventDate: now.Add(-3 * time.Hour)})
+	history.Add("item", HistoryRecord{StatusCode: 2, EventDate: now.Add(-30 * time.Minute)})
+	if got := historyCodes(history.Get("item", time.Time{}, time.Time{})); !slices.Equal(got, []int{2}) {
+		t.Errorf("expired on add: got %v", got)
+	}
+	// expired records are pruned from the ring start and free their slots
+	for i := 3; i <= 7; i++ {
+		history.Add("item", HistoryRecord{StatusCode: i, EventDate: now})
+	}
+	if got := historyCodes(history.Get("item", time.Time{}, time.Time{})); !slices.Equal(got, []int{3, 4, 5, 6, 7}) {
+		t.Errorf("after wrap: got %v", got)
+	}
+
+	// records expiring after the last add are hidden by Get
+	history = NewHistory(5, time.Hour)
+	history.Add("item", HistoryRecord{StatusCode: 1, EventDate: now.Add(-time.Hour + 50*time.Millisecond)})
+	history.Add("item", HistoryRecord{StatusCode: 2, EventDate: now})
+	time.Sleep(100 * time.Millisecond)
+	if got := historyCodes(history.Get("item", time.Time{}, time.Time{})); !slices.Equal(got, []int{2}) {
+		t.Errorf("expired on get: got %v", got)
+	}
+}
+
+func TestHistoryGrowsToLimit(t *testing.T) {
+	now := time.Now()
+	history := NewHistory(1000, time.Hour)
+	history.Add("item", HistoryRecord{StatusCode: 1, EventDate: now})
+	history.Add("item", HistoryRecord{StatusCode: 2, EventDate: now})
+	if size := len(history.items["item"].records); size != 2 {
+		t.Errorf("ring of %d records, want it grown by adds", size)
+	}
+
+	// expired records move the ring start, growing keeps the order
+	history = NewHistory(4, time.Hour)
+	history.Add("item", HistoryRecord{StatusCode: 1, EventDate: now.Add(-time.Hour + 50*time.Millisecond)})
+	history.Add("item", HistoryRecord{StatusCode: 2, EventDate: now})
+	time.Sleep(100 * time.Millisecond)
+	for i := 3; i <= 6; i++ {
+		history.Add("item", HistoryRecord{StatusCode: i, EventDate: now})
+	}
+	if got := historyCodes(history.Get("item", time.Time{}, time.Time{})); !slices.Equal(got, []int{3, 4, 5, 6}) {
+		t.Errorf("after growth: got %v", got)
+	}
+}
@@ -2,98 +2,391 @@ package pinger
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"time"
+
+	"observer/pkg/defaults"
 )
 
 const (
 	StatusSuccess = "success"
 	StatusFailure = "failure"
 	StatusCustom  = "custom"
+	StatusTimeout = "timeout"
+)
+
+const (
+	KindPing  = "ping"
+	KindWeb   = "web"
+	KindTcp   = "tcp"
+	KindDns   = "dns"
+	KindCert  = "certificate"
+	KindGrpc  = "grpc"
+	KindSteps = "steps"
 )
 
 type ItemsGroup struct {
-	Timeout time.Duration `json:"timeout"`
-	Items   []Item        `json:"items"`
+	Timeout        time.Duration `json:"timeout" yaml:"timeout"`
+	RequestTimeout time.Duration `json:"request_timeout" yaml:"request_timeout"`
+	Proxy          *Proxy        `json:"proxy" yaml:"proxy"`
+	Schedule       *Schedule     `json:"schedule" yaml:"schedule"`
+	Items          []Item        `json:"items" yaml:"items"`
+}
+
+// Schedule runs items every interval (the group timeout by default) or by the cron expression,
+// jitter delays every run randomly, spread shifts the first runs of the items sharing the schedule evenly over the interval,
+// immediate runs items on start instead of after the first interval
+type Schedule struct {
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+	Cron      string        `json:"cron" yaml:"cron"`
+	Jitter    time.Duration `json:"jitter" yaml:"jitter"`
+	Spread    bool          `json:"spread" yaml:"spread"`
+	Immediate bool          `json:"immediate" yaml:"immediate"`
 }
 
 type Item struct {
-	Id      interface{} `json:"id"`
-	Name    string      `json:"name"`
-	Order   int         `json:"order"`
-	Request Request     `json:"request"`
-	Status  Status      `json:"status"`
+	Id          interface{} `json:"id" yaml:"id"`
+	Name        string      `json:"name" yaml:"name"`
+	Order       int         `json:"order" yaml:"order"`
+	Request     Request     `json:"request" yaml:"request"`
+	Steps       []Step      `json:"steps" yaml:"steps"`
+	KeepCookies bool        `json:"keep_cookies" yaml:"keep_cookies"`
+	Schedule    *Schedule   `json:"schedule" yaml:"schedule"`
+	Status      Status      `json:"status" yaml:"status"`
+}
+
+// Step is a web request of a multi-step item, extracted values are available in later steps as {{.name}}
+type Step struct {
+	Name    string    `json:"name" yaml:"name"`
+	Request Request   `json:"request" yaml:"request"`
+	Extract []Extract `json:"extract" yaml:"extract"`
+}
+
+// Extract takes a variable value from the step response by one of the sources
+type Extract struct {
+	Name     string `json:"name" yaml:"name"`
+	JsonPath string `json:"json_path" yaml:"json_path"`
+	Regex    string `json:"regex" yaml:"regex"`
+	Header   string `json:"header" yaml:"header"`
+	Cookie   string `json:"cookie" yaml:"cookie"`
 }
 
 type Status struct {
-	Name          string    `json:"name"`
-	EventsCount   int       `json:"events_count"`
-	LastEventDate time.Time `json:"last_event_date"`
-	LastCode      int       `json:"last_code"`
+	Name               string    `json:"name" yaml:"name"`
+	EventsCount        int       `json:"events_count" yaml:"events_count"`
+	LastEventDate      time.Time `json:"last_event_date" yaml:"last_event_date"`
+	LastCode           int       `json:"last_code" yaml:"last_code"`
+	LastError          string    `json:"last_error" yaml:"last_error"`
+	ConsecutiveSuccess int       `json:"consecutive_success" yaml:"consecutive_success"`
+	ConsecutiveFailure int       `json:"consecutive_failure" yaml:"consecutive_failure"`
+	Since              time.Time `json:"since" yaml:"since"`
+}
+
+// TimeInState returns how long the item is in the current status
+func (s Status) TimeInState(now time.Time) time.Duration {
+	if s.Since.IsZero() {
+		return 0
+	}
+	return now.Sub(s.Since)
 }
 
 type Trigger struct {
-	Antispam     *time.Duration `json:"antispam"`
-	SkipBy       int            `json:"skip_by"`
-	OnSuccessful *Request       `json:"on_successful"`
-	OnFail       *Request       `json:"on_fail"`
-	Always       *Request       `json:"always"`
+	Antispam     *time.Duration `json:"antispam" yaml:"antispam"`
+	SkipBy       int            `json:"skip_by" yaml:"skip_by"`
+	OnSuccessful *Request       `json:"on_successful" yaml:"on_successful"`
+	OnFail       *Request       `json:"on_fail" yaml:"on_fail"`
+	Always       *Request       `json:"always" yaml:"always"`
 }
 
-type History struct { // TODO: implement
-	ItemId      interface{}           `json:"item_id"`
-	ItemName    string                `json:"item_name"`
-	EventDate   time.Time             `json:"event_date"`
-	LastRequest Request               `json:"last_request"`
-	Requests    map[time.Time]Request `json:"requests"`
+type HistoryRecord struct {
+	ItemId     interface{}   `json:"item_id" yaml:"item_id"`
+	ItemName   string        `json:"item_name" yaml:"item_name"`
+	EventDate  time.Time     `json:"event_date" yaml:"event_date"`
+	Latency    time.Duration `json:"latency" yaml:"latency"`
+	StatusCode int           `json:"status_code" yaml:"status_code"`
+	Error      string        `json:"error" yaml:"error"`
+	ErrorKind  string        `json:"error_kind" yaml:"error_kind"`
+	Successful bool          `json:"successful" yaml:"successful"`
+	State      string        `json:"state" yaml:"state"`
+	Ping       *PingResult   `json:"ping" yaml:"ping"`
 }
 
 type Request struct {
-	Method   string              `json:"method"`
-	Url      string              `json:"url"`
-	Body     string              `json:"body"`
-	Header   map[string][]string `json:"header"`
-	Proxy    *Proxy              `json:"proxy"`
-	Ping     string              `json:"address"`
-	Repeat   int                 `json:"repeat"`
-	Timeout  time.Duration       `json:"timeout"`
-	Response Response            `json:"response"`
-	Trigger  *Trigger            `json:"trigger"`
+	Method      string              `json:"method" yaml:"method"`
+	Url         string              `json:"url" yaml:"url"`
+	Body        string              `json:"body" yaml:"body"`
+	Header      map[string][]string `json:"header" yaml:"header"`
+	Proxy       *Proxy              `json:"proxy" yaml:"proxy"`
+	Proxies     []Proxy             `json:"proxies" yaml:"proxies"`
+	Ping        string              `json:"address" yaml:"address"`
+	Repeat      int                 `json:"repeat" yaml:"repeat"`
+	Privileged  bool                `json:"privileged" yaml:"privileged"`
+	Timeout     time.Duration       `json:"timeout" yaml:"timeout"`
+	Response    Response            `json:"response" yaml:"response"`
+	Trigger     *Trigger            `json:"trigger" yaml:"trigger"`
+	Tcp         *Tcp                `json:"tcp" yaml:"tcp"`
+	Dns         *Dns                `json:"dns" yaml:"dns"`
+	Certificate *Certificate        `json:"certificate" yaml:"certificate"`
+	Grpc        *Grpc               `json:"grpc" yaml:"grpc"`
+	Retry       *Retry              `json:"retry" yaml:"retry"`
+	Tls         *ClientTls          `json:"tls" yaml:"tls"`
+	Auth        *Auth               `json:"auth" yaml:"auth"`
+	Redirect    *Redirect           `json:"redirect" yaml:"redirect"`
+}
+
+// Redirect sets whether web checks follow redirects and the max hops count, 10 by default,
+// not followed redirects are checked as the response
+type Redirect struct {
+	Follow  *bool `json:"follow" yaml:"follow"`
+	MaxHops int   `json:"max_hops" yaml:"max_hops"`
+}
+
+// ClientTls configures TLS of web checks, cert and key are the client certificate files,
+// ca replaces system roots by the PEM bundle
+type ClientTls struct {
+	Cert               string `json:"cert" yaml:"cert"`
+	Key                string `json:"key" yaml:"key"`
+	Ca                 string `json:"ca" yaml:"ca"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+	ServerName         string `json:"server_name" yaml:"server_name"`
+}
+
+// Auth sets the Authorization header of web checks, exactly one method is used
+type Auth struct {
+	Basic  *BasicAuth `json:"basic" yaml:"basic"`
+	Bearer string     `json:"bearer" yaml:"bearer"`
+	OAuth2 *OAuth2    `json:"oauth2" yaml:"oauth2"`
+}
+
+type BasicAuth struct {
+	User string `json:"user" yaml:"user"`
+	Pass string `json:"pass" yaml:"pass"`
+}
+
+// OAuth2 fetches bearer tokens by the client credentials grant, tokens are cached until expiry
+type OAuth2 struct {
+	TokenUrl          string   `json:"token_url" yaml:"token_url"`
+	ClientId          string   `json:"client_id" yaml:"client_id"`
+	ClientSecret      string   `json:"client_secret" yaml:"client_secret"`
+	Scopes            []string `json:"scopes" yaml:"scopes"`
+	Audience          string   `json:"audience" yaml:"audience"`
+	CredentialsInBody bool     `json:"credentials_in_body" yaml:"credentials_in_body"`
+}
+
+// Retry repeats failed checks before the result is recorded, attempts include the first one,
+// backoff grows by multiplier up to max_backoff, on lists the retriable error kinds
+type Retry struct {
+	Attempts   int           `json:"attempts" yaml:"attempts"`
+	Backoff    time.Duration `json:"backoff" yaml:"backoff"`
+	Multiplier float64       `json:"multiplier" yaml:"multiplier"`
+	MaxBackoff time.Duration `json:"max_backoff" yaml:"max_backoff"`
+	On         []string      `json:"on" yaml:"on"`
+}
+
+// Kind returns the check type by the configured target
+func (r Request) Kind() string {
+	switch {
+	case r.Ping != "":
+		return KindPing
+	case r.Tcp != nil:
+		return KindTcp
+	case r.Dns != nil:
+		return KindDns
+	case r.Certificate != nil:
+		return KindCert
+	case r.Grpc != nil:
+		return KindGrpc
+	case getHost(r.Url) != "":
+		return KindWeb
+	}
+	return ""
+}
+
+// Target returns the checked address of any kind
+func (r Request) Target() string {
+	if r.Tcp != nil {
+		return r.Tcp.Address
+	}
+	if r.Dns != nil {
+		return r.Dns.Name
+	}
+	if r.Certificate != nil {
+		return r.Certificate.Address
+	}
+	if r.Grpc != nil {
+		return r.Grpc.Address
+	}
+	return defaults.Str(r.Url, r.Ping)
+}
+
+type Tcp struct {
+	Address string `json:"address" yaml:"address"`
+	Send    string `json:"send" yaml:"send"`
+	Expect  string `json:"expect" yaml:"expect"`
+}
+
+type Dns struct {
+	Name     string        `json:"name" yaml:"name"`
+	Type     string        `json:"type" yaml:"type"`
+	Resolver string        `json:"resolver" yaml:"resolver"`
+	Expect   []string      `json:"expect" yaml:"expect"`
+	MinTtl   time.Duration `json:"min_ttl" yaml:"min_ttl"`
+	MaxTtl   time.Duration `json:"max_ttl" yaml:"max_ttl"`
+	MaxTime  time.Duration `json:"max_time" yaml:"max_time"`
+}
+
+type Certificate struct {
+	Address      string `json:"address" yaml:"address"`
+	ServerName   string `json:"server_name" yaml:"server_name"`
+	WarningDays  int    `json:"warning_days" yaml:"warning_days"`
+	CriticalDays int    `json:"critical_days" yaml:"critical_days"`
+}
+
+type Grpc struct {
+	Address            string `json:"address" yaml:"address"`
+	Service            string `json:"service" yaml:"service"`
+	Tls                bool   `json:"tls" yaml:"tls"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+	ServerName         string `json:"server_name" yaml:"server_name"`
+}
+
+type CertificateResult struct {
+	Subject  string    `json:"subject" yaml:"subject"`
+	Issuer   string    `json:"issuer" yaml:"issuer"`
+	SANs     []string  `json:"sans" yaml:"sans"`
+	NotAfter time.Time `json:"not_after" yaml:"not_after"`
+	DaysLeft int       `json:"days_left" yaml:"days_left"`
+	Version  string    `json:"version" yaml:"version"`
+	Verified bool      `json:"verified" yaml:"verified"`
 }
 
 type Response struct {
-	Status   ItemResultStatus `json:"status"`
-	Body     *ResponseBody    `json:"body"`
-	SaveBody bool             `json:"save_body"`
+	Status   ItemResultStatus `json:"status" yaml:"status"`
+	Body     *ResponseBody    `json:"body" yaml:"body"`
+	SaveBody bool             `json:"save_body" yaml:"save_body"`
+	Timing   *HttpTiming      `json:"timing" yaml:"timing"`
+	Ping     *PingLimits      `json:"ping" yaml:"ping"`
+	FinalUrl string           `json:"final_url" yaml:"final_url"`
+	Location string           `json:"location" yaml:"location"`
+}
+
+// PingLimits sets the packet loss percent and the average rtt above which the item is degraded or down,
+// an omitted fail_loss is OBSERVER_PINGER_PING_FAIL_LOSS
+type PingLimits struct {
+	DegradedLoss float64       `json:"degraded_loss" yaml:"degraded_loss"`
+	DegradedAvg  time.Duration `json:"degraded_avg" yaml:"degraded_avg"`
+	FailLoss     *float64      `json:"fail_loss" yaml:"fail_loss"`
+	FailAvg      time.Duration `json:"fail_avg" yaml:"fail_avg"`
+}
+
+// withFailLoss returns the limits with the fail loss set when it is omitted
+func (l PingLimits) withFailLoss(failLoss float64) PingLimits {
+	if l.FailLoss == nil {
+		l.FailLoss = &failLoss
+	}
+	return l
+}
+
+// assert compares the statistics with the fail or the degraded limits, zero rtt limits are ignored,
+// the fail loss is set by withFailLoss
+func (l PingLimits) assert(stats *PingResult, degraded bool) []AssertionResult {
+	name, loss, avg := "ping.fail", 0.0, l.FailAvg
+	if l.FailLoss != nil {
+		loss = *l.FailLoss
+	}
+	if degraded {
+		name, loss, avg = "ping.degraded", l.DegradedLoss, l.DegradedAvg
+	}
+	assertions := []AssertionResult{{
+		Name:     name + "_loss",
+		Expected: fmt.Sprintf("<= %.1f%%", loss),
+		Actual:   fmt.Sprintf("%.1f%%", stats.Loss),
+		Passed:   stats.Loss <= loss,
+	}}
+	if avg > 0 {
+		assertions = append(assertions, AssertionResult{
+			Name:     name + "_avg",
+			Expected: "<= " + avg.String(),
+			Actual:   stats.Avg.String(),
+			Passed:   stats.Received > 0 && stats.Avg <= avg,
+		})
+	}
+	return assertions
+}
+
+// HttpTiming is the web request phases breakdown, in Response it sets the phases limits
+type HttpTiming struct {
+	DnsLookup       time.Duration `json:"dns_lookup" yaml:"dns_lookup"`
+	TcpConnect      time.Duration `json:"tcp_connect" yaml:"tcp_connect"`
+	TlsHandshake    time.Duration `json:"tls_handshake" yaml:"tls_handshake"`
+	FirstByte       time.Duration `json:"first_byte" yaml:"first_byte"`
+	ContentTransfer time.Duration `json:"content_transfer" yaml:"content_transfer"`
+	Total           time.Duration `json:"total" yaml:"total"`
 }
 
 type ItemResultStatus struct {
-	Code int   `json:"code"`
-	Min  int   `json:"min"`
-	Max  int   `json:"max"`
-	List []int `json:"list"`
+	Code int   `json:"code" yaml:"code"`
+	Min  int   `json:"min" yaml:"min"`
+	Max  int   `json:"max" yaml:"max"`
+	List []int `json:"list" yaml:"list"`
 }
 
 type ResponseBody struct {
-	Full    string `json:"full"`
-	Contain string `json:"contain"`
-	Regex   string `json:"regex"`
-	Grep    *Grep  `json:"grep"`
+	Full    string `json:"full" yaml:"full"`
+	Contain string `json:"contain" yaml:"contain"`
+	Regex   string `json:"regex" yaml:"regex"`
+	Grep    *Grep  `json:"grep" yaml:"grep"`
 }
 
 type ResponseResult struct {
-	Successful bool   `json:"successful"`
-	StatusCode int    `json:"status_code"`
-	Body       string `json:"body"`
-	Error      string `json:"error"`
+	Successful  bool               `json:"successful" yaml:"successful"`
+	StatusCode  int                `json:"status_code" yaml:"status_code"`
+	Body        string             `json:"body" yaml:"body"`
+	Error       string             `json:"error" yaml:"error"`
+	ErrorKind   string             `json:"error_kind" yaml:"error_kind"`
+	Attempts    int                `json:"attempts" yaml:"attempts"`
+	Date        time.Time          `json:"date" yaml:"date"`
+	Latency     time.Duration      `json:"latency" yaml:"latency"`
+	Assertions  []AssertionResult  `json:"assertions" yaml:"assertions"`
+	Warning     string             `json:"warning" yaml:"warning"`
+	Certificate *CertificateResult `json:"certificate" yaml:"certificate"`
+	Timing      *HttpTiming        `json:"timing" yaml:"timing"`
+	Ping        *PingResult        `json:"ping" yaml:"ping"`
+	FinalUrl    string             `json:"final_url" yaml:"final_url"`
+	Redirects   int                `json:"redirects" yaml:"redirects"`
+}
+
+type PingResult struct {
+	Sent     int           `json:"sent" yaml:"sent"`
+	Received int           `json:"received" yaml:"received"`
+	Loss     float64       `json:"loss" yaml:"loss"`
+	Min      time.Duration `json:"min" yaml:"min"`
+	Avg      time.Duration `json:"avg" yaml:"avg"`
+	Max      time.Duration `json:"max" yaml:"max"`
+	StdDev   time.Duration `json:"std_dev" yaml:"std_dev"`
+}
+
+// State returns the status name by the result, successful results with a warning are custom
+func (rr ResponseResult) State() string {
+	switch {
+	case !rr.Successful && rr.ErrorKind == ErrorTimeout:
+		return StatusTimeout
+	case !rr.Successful:
+		return StatusFailure
+	case rr.Warning != "":
+		return StatusCustom
+	}
+	return StatusSuccess
 }
 
 func (rr ResponseResult) WithErr(format string, err error) ResponseResult {
 	if err != nil {
 		rr.Error = fmt.Sprintf(format, err.Error())
+		rr.ErrorKind = classifyError(err)
 	} else {
 		rr.Error = format
 	}
@@ -106,16 +399,24 @@ func (rr ResponseResult) SetErr(e string) ResponseResult {
 }
 
 type Grep struct {
-	Xpath    string `json:"xpath"`
-	JsonPath string `json:"json_path"`
+	Xpath    string `json:"xpath" yaml:"xpath"`
+	JsonPath string `json:"json_path" yaml:"json_path"`
+	Operator string `json:"operator" yaml:"operator"`
+	Value    string `json:"value" yaml:"value"`
 }
 
+// Proxy is an http, https or socks5 proxy, the scheme of host selects the type (http by default),
+// tunnel makes http proxies use CONNECT for plain http targets too,
+// key is sent as a bearer Proxy-Authorization or as the key_header value
 type Proxy struct {
-	Host string `json:"host"`
-	Port string `json:"port"`
-	User string `json:"user"`
-	Pass string `json:"pass"`
-	Key  string `json:"key"`
+	Name      string `json:"name" yaml:"name"`
+	Host      string `json:"host" yaml:"host"`
+	Port      string `json:"port" yaml:"port"`
+	User      string `json:"user" yaml:"user"`
+	Pass      string `json:"pass" yaml:"pass"`
+	Key       string `json:"key" yaml:"key"`
+	KeyHeader string `json:"key_header" yaml:"key_header"`
+	Tunnel    bool   `json:"tunnel" yaml:"tunnel"`
 }
 
 func PingItem(address string, duration time.Duration, repeat int) Item {
@@ -144,12 +445,28 @@ func CheckStatusItem(url string) Item {
 	return newItem
 }
 
+// Kind returns the check type of the item
+func (i Item) Kind() string {
+	if len(i.Steps) > 0 {
+		return KindSteps
+	}
+	return i.Request.Kind()
+}
+
+// Key returns the identifier used to track the item between runs
+func (i Item) Key() string {
+	if i.Id != nil {
+		return fmt.Sprintf("%v", i.Id)
+	}
+	return defaults.Str(i.Name, i.Request.Target())
+}
+
 func (i Item) CheckFullBody(body string) Item {
 	i.Request.Response.Body.Full = body
 	return i
 }
 
-func (i Item) buildRequest() (*http.Request, error) {
+func (i Item) buildRequest(ctx context.Context) (*http.Request, error) {
 	var body io.Reader
 	var err error
 	req := &http.Request{}
@@ -191,6 +508,14 @@ func (i Item) buildRequest() (*http.Request, error) {
 	//	Cancel:           nil,
 	//	Response:         nil,
 	//}
-	req, err = http.NewRequest(i.Request.Method, i.Request.Url, body)
+	req, err = http.NewRequestWithContext(ctx, i.Request.Method, i.Request.Url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range i.Request.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
 	return req, nil
 }
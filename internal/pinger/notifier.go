@@ -0,0 +1,77 @@
+package pinger
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	notifierUp   = "up"
+	notifierDown = "down"
+)
+
+// notifier tracks the declared item state, triggers are called only when it is changed
+type notifier struct {
+	items map[string]*notifierState
+	mutex *sync.Mutex
+}
+
+type notifierState struct {
+	state        string
+	notified     string
+	failures     int
+	lastNotified time.Time
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		items: make(map[string]*notifierState),
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Transition applies the result to the item state and reports whether triggers should be called:
+// the item is down after SkipBy consecutive failures, up after the first success,
+// unknown->up is not notified and a change within the antispam window is postponed
+// until the first result after the window when the state still differs from the notified one
+func (n *notifier) Transition(key string, trigger Trigger, successful bool, date time.Time) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	item, ok := n.items[key]
+	if !ok {
+		item = &notifierState{}
+		n.items[key] = item
+	}
+	if successful {
+		item.failures = 0
+		item.state = notifierUp
+	} else {
+		item.failures++
+		if item.failures >= trigger.SkipBy {
+			item.state = notifierDown
+		}
+	}
+	if item.notified == "" && item.state == notifierUp {
+		item.notified = notifierUp
+	}
+	if item.state == "" || item.state == item.notified {
+		return false
+	}
+	if trigger.Antispam != nil && date.Sub(item.lastNotified) < *trigger.Antispam {
+		return false
+	}
+	item.notified = item.state
+	item.lastNotified = date
+	return true
+}
+
+// prune drops the states of items missing in keys
+func (n *notifier) prune(keys map[string]bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for key := range n.items {
+		if !keys[key] {
+			delete(n.items, key)
+		}
+	}
+}
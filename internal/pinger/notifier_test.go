@@ -0,0 +1,48 @@
+package pinger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotifierTransition(t *testing.T) {
+	antispam := 2 * time.Minute
+	trigger := Trigger{Antispam: &antispam, SkipBy: 1}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name       string
+		successful bool
+		after      time.Duration
+		notify     bool
+	}{
+		{"unknown to up is silent", true, 0, false},
+		{"up to down", false, time.Second, true},
+		{"up within the window is postponed", true, 2 * time.Second, false},
+		{"still up after the window", true, 5 * time.Minute, true},
+		{"no change", true, 6 * time.Minute, false},
+		{"down after the window", false, 10 * time.Minute, true},
+		{"flapping up within the window", true, 10*time.Minute + time.Second, false},
+		{"back down within the window", false, 10*time.Minute + 2*time.Second, false},
+		{"down after the window is already notified", false, 15 * time.Minute, false},
+	}
+	n := newNotifier()
+	for _, step := range steps {
+		if got := n.Transition("item", trigger, step.successful, start.Add(step.after)); got != step.notify {
+			t.Errorf("%s: notify %v, want %v", step.name, got, step.notify)
+		}
+	}
+}
+
+func TestNotifierSkipBy(t *testing.T) {
+	trigger := Trigger{SkipBy: 3}
+	n := newNotifier()
+	date := time.Now()
+	for i, want := range []bool{false, false, true, false} {
+		if got := n.Transition("item", trigger, false, date); got != want {
+			t.Errorf("failure %d: notify %v, want %v", i+1, got, want)
+		}
+	}
+	if !n.Transition("item", trigger, true, date) {
+		t.Error("down to up is not notified")
+	}
+}
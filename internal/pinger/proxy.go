@@ -0,0 +1,196 @@
+package pinger
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var proxyPorts = map[string]string{
+	"http":    "80",
+	"https":   "443",
+	"socks5":  "1080",
+	"socks5h": "1080",
+}
+
+// url returns the proxy address with the port and the user credentials
+func (p Proxy) url() (*url.URL, error) {
+	host := p.Host
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	proxyURL, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := proxyPorts[proxyURL.Scheme]; !ok {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if p.Port != "" && proxyURL.Port() == "" {
+		proxyURL.Host = net.JoinHostPort(proxyURL.Hostname(), p.Port)
+	}
+	if p.User != "" {
+		proxyURL.User = url.UserPassword(p.User, p.Pass)
+	}
+	return proxyURL, nil
+}
+
+// label names the egress path of the proxy in item keys
+func (p Proxy) label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	if proxyURL, err := p.url(); err == nil {
+		return proxyURL.Host
+	}
+	return p.Host
+}
+
+// header returns the key auth header, basic auth of user is used without the key
+func (p Proxy) header(proxyURL *url.URL) http.Header {
+	header := http.Header{}
+	switch {
+	case p.Key != "" && p.KeyHeader != "":
+		header.Set(p.KeyHeader, p.Key)
+	case p.Key != "":
+		header.Set("Proxy-Authorization", "Bearer "+p.Key)
+	case proxyURL.User != nil:
+		auth := proxyURL.User.Username() + ":" + p.Pass
+		header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+	return header
+}
+
+// apply routes transport requests through the proxy, plain http targets of http proxies are forwarded
+// unless tunnel is set, other targets use CONNECT or socks5
+func (p Proxy) apply(transport *http.Transport) error {
+	proxyURL, err := p.url()
+	if err != nil {
+		return err
+	}
+	if p.Tunnel && (proxyURL.Scheme == "http" || proxyURL.Scheme == "https") {
+		transport.Proxy = nil
+		transport.DialContext = connectDialer(proxyURL, p.header(proxyURL))
+		return nil
+	}
+	transport.Proxy = http.ProxyURL(proxyURL)
+	transport.ProxyConnectHeader = p.header(proxyURL)
+	return nil
+}
+
+// authorize adds the key auth header to plain http requests forwarded by the http proxy
+func (p Proxy) authorize(request *http.Request) error {
+	proxyURL, err := p.url()
+	if err != nil {
+		return err
+	}
+	if p.Tunnel || request.URL.Scheme != "http" || strings.HasPrefix(proxyURL.Scheme, "socks5") {
+		return nil
+	}
+	for name, values := range p.header(proxyURL) {
+		request.Header[name] = values
+	}
+	return nil
+}
+
+// connectDialer opens connections through the http proxy tunnel established by CONNECT
+func connectDialer(proxyURL *url.URL, header http.Header) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		proxyAddress := proxyURL.Host
+		if proxyURL.Port() == "" {
+			proxyAddress = net.JoinHostPort(proxyURL.Hostname(), proxyPorts[proxyURL.Scheme])
+		}
+		var dialer interface {
+			DialContext(ctx context.Context, network, address string) (net.Conn, error)
+		} = &net.Dialer{}
+		if proxyURL.Scheme == "https" {
+			dialer = &tls.Dialer{Config: &tls.Config{ServerName: proxyURL.Hostname()}}
+		}
+		conn, err := dialer.DialContext(ctx, network, proxyAddress)
+		if err != nil {
+			return nil, err
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		connect := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: address},
+			Host:   address,
+			Header: header,
+		}
+		if err = connect.Write(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy connect err: %w", err)
+		}
+		response, err := http.ReadResponse(bufio.NewReader(conn), connect)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy connect err: %w", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy connect %s: %s", address, response.Status)
+		}
+		_ = conn.SetDeadline(time.Time{})
+		return conn, nil
+	}
+}
+
+// viaProxies splits the item checked through several proxies into an item per proxy,
+// every egress path gets its own key, status and history
+func (i Item) viaProxies() []Item {
+	if len(i.Request.Proxies) == 0 {
+		return []Item{i}
+	}
+	items := make([]Item, 0, len(i.Request.Proxies))
+	for _, proxy := range i.Request.Proxies {
+		item := i
+		proxy := proxy
+		item.Id = i.Key() + "@" + proxy.label()
+		if i.Name != "" {
+			item.Name = i.Name + " via " + proxy.label()
+		}
+		item.Request.Proxy = &proxy
+		item.Request.Proxies = nil
+		items = append(items, item)
+	}
+	return items
+}
+
+func (p Proxy) validate(path string, errs *ValidationErrors) {
+	if p.Host == "" {
+		errs.add(path+".host", "required")
+		return
+	}
+	proxyURL, err := p.url()
+	if err != nil {
+		errs.add(path+".host", "%s", err)
+		return
+	}
+	if proxyURL.Hostname() == "" {
+		errs.add(path+".host", "host required, got %q", p.Host)
+	}
+	if strings.HasPrefix(proxyURL.Scheme, "socks5") {
+		if p.Key != "" {
+			errs.add(path+".key", "not supported by socks5 proxies, use user and pass")
+		}
+		if p.Tunnel {
+			errs.add(path+".tunnel", "not supported by socks5 proxies")
+		}
+	}
+	if p.Key != "" && p.KeyHeader == "" && p.User != "" {
+		errs.add(path+".key", "key and user both set Proxy-Authorization, set key_header or remove one")
+	}
+	if p.KeyHeader != "" && p.Key == "" {
+		errs.add(path+".key", "required with key_header")
+	}
+}
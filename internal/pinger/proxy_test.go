@@ -0,0 +1,99 @@
+package pinger
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testProxy is a CONNECT and forwarding proxy accepting requests with the header value
+type testProxy struct {
+	header, value string
+	connects      int
+	forwards      int
+	mutex         *sync.Mutex
+}
+
+func (p *testProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	if r.Method == http.MethodConnect {
+		p.connects++
+	} else {
+		p.forwards++
+	}
+	p.mutex.Unlock()
+	if r.Header.Get(p.header) != p.value {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+	if r.Method != http.MethodConnect {
+		_, _ = io.WriteString(w, "forwarded")
+		return
+	}
+	target, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	conn, buffer, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() {
+		_, _ = io.Copy(target, buffer)
+		target.Close()
+	}()
+	_, _ = io.Copy(conn, target)
+	conn.Close()
+}
+
+func TestProxyAuthorization(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "target")
+	}))
+	defer target.Close()
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	cases := []struct {
+		name          string
+		proxy         Proxy
+		header, value string
+		body          string
+		error         string
+	}{
+		{"bearer key", Proxy{Key: "secret", Tunnel: true}, "Proxy-Authorization", "Bearer secret", "target", ""},
+		{"key header", Proxy{Key: "secret", KeyHeader: "X-Proxy-Key", Tunnel: true}, "X-Proxy-Key", "secret", "target", ""},
+		{"basic", Proxy{User: "user", Pass: "pass", Tunnel: true}, "Proxy-Authorization", basic, "target", ""},
+		{"forwarded with key", Proxy{Key: "secret"}, "Proxy-Authorization", "Bearer secret", "forwarded", ""},
+		{"rejected connect", Proxy{Key: "wrong", Tunnel: true}, "Proxy-Authorization", "Bearer secret", "", "407 Proxy Authentication Required"},
+	}
+	for _, c := range cases {
+		handler := &testProxy{header: c.header, value: c.value, mutex: &sync.Mutex{}}
+		proxy := httptest.NewServer(handler)
+		c.proxy.Host = proxy.URL
+		item := Item{Name: c.name, Request: Request{Url: target.URL, Timeout: time.Second, Proxy: &c.proxy}}
+		result := (&Data{}).web(context.Background(), item)
+		proxy.Close()
+		if c.error != "" {
+			if result.Successful || !strings.Contains(result.Error, c.error) {
+				t.Errorf("%s: result %v %q, want error %q", c.name, result.Successful, result.Error, c.error)
+			}
+		} else if !result.Successful || result.Body != c.body {
+			t.Errorf("%s: result %v %q body %q, want %q", c.name, result.Successful, result.Error, result.Body, c.body)
+		}
+		if c.proxy.Tunnel && (handler.connects != 1 || handler.forwards != 0) {
+			t.Errorf("%s: %d connects %d forwards, want a tunnel", c.name, handler.connects, handler.forwards)
+		}
+		if !c.proxy.Tunnel && (handler.connects != 0 || handler.forwards != 1) {
+			t.Errorf("%s: %d connects %d forwards, want a forwarded request", c.name, handler.connects, handler.forwards)
+		}
+	}
+}
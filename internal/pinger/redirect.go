@@ -0,0 +1,101 @@
+package pinger
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+)
+
+const defaultRedirectHops = 10
+
+// policy returns the client CheckRedirect func, not followed redirects return the 3xx response
+func (r *Redirect) policy() func(request *http.Request, via []*http.Request) error {
+	if r != nil && r.Follow != nil && !*r.Follow {
+		return func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	hops := defaultRedirectHops
+	if r != nil && r.MaxHops > 0 {
+		hops = r.MaxHops
+	}
+	return func(request *http.Request, via []*http.Request) error {
+		if len(via) > hops {
+			return fmt.Errorf("stopped after %d redirects", hops)
+		}
+		return nil
+	}
+}
+
+// redirects counts the followed redirects of the response
+func redirects(response *http.Response) int {
+	count := 0
+	for request := response.Request; request != nil && request.Response != nil; request = request.Response.Request {
+		count++
+	}
+	return count
+}
+
+// assertRedirect matches the final url and the Location header by the configured regexes
+func (r Response) assertRedirect(finalUrl, location string) []AssertionResult {
+	assertions := make([]AssertionResult, 0)
+	if r.FinalUrl != "" {
+		assertions = append(assertions, regexAssertion("final_url", r.FinalUrl, finalUrl))
+	}
+	if r.Location != "" {
+		assertions = append(assertions, regexAssertion("location", r.Location, location))
+	}
+	return assertions
+}
+
+func regexAssertion(name, pattern, value string) AssertionResult {
+	assertion := AssertionResult{
+		Name:     name,
+		Expected: pattern,
+		Actual:   strconv.Quote(value),
+	}
+	if re, err := regexp.Compile(pattern); err != nil {
+		assertion.Actual = err.Error()
+	} else {
+		assertion.Passed = re.MatchString(value)
+	}
+	return assertion
+}
+
+// cookieJar returns the jar kept across runs of the item, a new jar is used without keep_cookies
+func (d *Data) cookieJar(item Item) (http.CookieJar, error) {
+	if !item.KeepCookies {
+		return cookiejar.New(nil)
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if jar, ok := d.jars[item.Key()]; ok {
+		return jar, nil
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	d.jars[item.Key()] = jar
+	return jar, nil
+}
+
+// pruneJars drops the kept cookies of items missing in keys, the caller holds the mutex
+func (d *Data) pruneJars(keys map[string]bool) {
+	for key := range d.jars {
+		if !keys[key] {
+			delete(d.jars, key)
+		}
+	}
+}
+
+func (r Redirect) validate(path string, errs *ValidationErrors) {
+	if r.MaxHops < 0 {
+		errs.add(path+".max_hops", "must not be negative")
+	}
+	if r.MaxHops > 0 && r.Follow != nil && !*r.Follow {
+		errs.add(path+".max_hops", "redirects are not followed")
+	}
+}
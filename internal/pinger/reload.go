@@ -0,0 +1,124 @@
+package pinger
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Reload reads the config file again and restarts only the changed groups
+func (d *Data) Reload(ctx context.Context) error {
+	config, err := LoadConfig(d.configFile)
+	if err != nil {
+		return err
+	}
+	d.logger.Info(ctx, "reload config", "config", d.configFile)
+	d.apply(ctx, config)
+	return nil
+}
+
+// apply starts senders for new and modified groups and stops the ones missing in config,
+// unchanged groups keep running
+func (d *Data) apply(ctx context.Context, config Config) {
+	groups := make([]ItemsGroup, 0, len(config.ItemsGroup))
+	for _, group := range config.ItemsGroup {
+		group.Items = d.withDefaults(group)
+		groups = append(groups, group)
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.runCtx == nil {
+		d.logger.Warn(ctx, "pinger is not running, config is not applied")
+		return
+	}
+	started, kept := 0, 0
+	senders := make(map[string]context.CancelFunc, len(groups))
+	for i, key := range groupKeys(groups) {
+		if cancel, ok := d.senders[key]; ok {
+			senders[key] = cancel
+			delete(d.senders, key)
+			kept++
+			continue
+		}
+		groupCtx, cancel := context.WithCancel(d.runCtx)
+		senders[key] = cancel
+		started++
+		d.sending.Add(1)
+		go func(group ItemsGroup) {
+			defer d.sending.Done()
+			defer d.recovered(groupCtx, "sender")
+			d.Sender(groupCtx, group)
+		}(groups[i])
+	}
+	for _, cancel := range d.senders {
+		cancel()
+	}
+	d.logger.Info(ctx, "items groups applied", "started", started, "kept", kept, "stopped", len(d.senders))
+	d.senders = senders
+	d.ItemsGroup = groups
+	d.prune(groups)
+	if len(groups) == 0 {
+		d.logger.Warn(ctx, "no items to observe", "config", d.configFile)
+	}
+}
+
+// prune drops cookies, history, trigger states and statuses of items removed from config,
+// the caller holds the mutex
+func (d *Data) prune(groups []ItemsGroup) {
+	keys := make(map[string]bool)
+	for _, group := range groups {
+		for _, item := range group.Items {
+			keys[item.Key()] = true
+		}
+	}
+	d.pruneJars(keys)
+	d.history.prune(keys)
+	d.notifier.prune(keys)
+	d.statuses.prune(keys)
+}
+
+// watch reloads config when the file modification time or size is changed
+func (d *Data) watch(ctx context.Context) {
+	modTime, size := fileVersion(d.configFile)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.settings.AfterSeconds("OBSERVER_PINGER_CONFIG_WATCH_SEC", 5):
+			newModTime, newSize := fileVersion(d.configFile)
+			if newModTime.Equal(modTime) && newSize == size {
+				continue
+			}
+			modTime, size = newModTime, newSize
+			if err := d.Reload(ctx); err != nil {
+				d.logger.Error(ctx, err, "reload config, previous items are kept")
+			}
+		}
+	}
+}
+
+func fileVersion(path string) (time.Time, int64) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// groupKeys identifies groups by content, identical groups are numbered by occurrence
+func groupKeys(groups []ItemsGroup) []string {
+	keys := make([]string, 0, len(groups))
+	seen := make(map[string]int)
+	for _, group := range groups {
+		data, _ := json.Marshal(group)
+		hash := sha1.Sum(data)
+		key := hex.EncodeToString(hash[:])
+		seen[key]++
+		keys = append(keys, fmt.Sprintf("%s#%d", key, seen[key]))
+	}
+	return keys
+}
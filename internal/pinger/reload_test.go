@@ -0,0 +1,41 @@
+package pinger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPruneRemovedItems(t *testing.T) {
+	d := testData()
+	trigger := Trigger{}
+	for _, key := range []string{"kept", "removed"} {
+		d.record(Item{Id: key}, ResponseResult{Successful: true, Date: time.Now()})
+		d.notifier.Transition(key, trigger, false, time.Now())
+		jar, err := d.cookieJar(Item{Id: key, KeepCookies: true})
+		if err != nil || jar == nil {
+			t.Fatalf("cookie jar %s: %v", key, err)
+		}
+	}
+	d.mutex.Lock()
+	d.prune([]ItemsGroup{{Items: []Item{{Id: "kept"}}}})
+	d.mutex.Unlock()
+
+	if keys := d.history.Keys(); len(keys) != 1 || keys[0] != "kept" {
+		t.Errorf("history keys %v, want kept", keys)
+	}
+	if _, ok := d.Status("removed"); ok {
+		t.Error("status of the removed item is kept")
+	}
+	if _, ok := d.Status("kept"); !ok {
+		t.Error("status of the kept item is dropped")
+	}
+	if _, ok := d.notifier.items["removed"]; ok {
+		t.Error("trigger state of the removed item is kept")
+	}
+	if _, ok := d.jars["removed"]; ok {
+		t.Error("cookie jar of the removed item is kept")
+	}
+	if len(d.jars) != 1 || len(d.notifier.items) != 1 {
+		t.Errorf("jars %d, trigger states %d, want the kept item", len(d.jars), len(d.notifier.items))
+	}
+}
@@ -0,0 +1,90 @@
+package pinger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	ErrorTimeout    = "timeout"
+	ErrorConnection = "connection"
+	ErrorServer     = "5xx"
+	ErrorAssertion  = "assertion"
+	ErrorAny        = "any"
+)
+
+var retryErrors = []string{ErrorTimeout, ErrorConnection, ErrorServer, ErrorAssertion, ErrorAny}
+
+var defaultRetryOn = []string{ErrorTimeout, ErrorConnection}
+
+// classifyError returns the error kind used by the retry policy
+func classifyError(err error) string {
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &opErr), errors.As(err, &dnsErr):
+		return ErrorConnection
+	}
+	return ""
+}
+
+// retriable reports whether the failed result matches any of the retry error kinds
+func (rr ResponseResult) retriable(on []string) bool {
+	if rr.Successful {
+		return false
+	}
+	if slices.Contains(on, ErrorAny) {
+		return true
+	}
+	if rr.ErrorKind != "" && slices.Contains(on, rr.ErrorKind) {
+		return true
+	}
+	if rr.StatusCode >= 500 && rr.StatusCode <= 599 && slices.Contains(on, ErrorServer) {
+		return true
+	}
+	return slices.Contains(on, ErrorAssertion) && failedAssertions(rr.Assertions) != ""
+}
+
+// checkWithRetry repeats the failed check by the item retry policy, the last result is returned
+func (d *Data) checkWithRetry(ctx context.Context, item Item) ResponseResult {
+	retry := item.Request.Retry
+	result := d.check(ctx, item)
+	result.Attempts = 1
+	if retry == nil {
+		return result
+	}
+	on := retry.On
+	if len(on) == 0 {
+		on = defaultRetryOn
+	}
+	backoff := retry.Backoff
+	for attempt := 2; attempt <= retry.Attempts && result.retriable(on); attempt++ {
+		d.logger.Debug(ctx, "retry check", "item", item.Key(), "attempt", attempt, "error", result.Error)
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(backoff):
+		}
+		date := result.Date
+		result = d.check(ctx, item)
+		result.Date = date
+		result.Attempts = attempt
+		if retry.Multiplier > 1 {
+			backoff = time.Duration(float64(backoff) * retry.Multiplier)
+		}
+		if retry.MaxBackoff > 0 && backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+	return result
+}
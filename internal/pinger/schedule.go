@@ -0,0 +1,160 @@
+package pinger
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// scheduleOf returns the item schedule, the group one is used when the item has none,
+// the group timeout is the default interval
+func (g ItemsGroup) scheduleOf(item Item) Schedule {
+	schedule := Schedule{}
+	switch {
+	case item.Schedule != nil:
+		schedule = *item.Schedule
+	case g.Schedule != nil:
+		schedule = *g.Schedule
+	}
+	if schedule.Interval == 0 && schedule.Cron == "" {
+		schedule.Interval = g.Timeout
+	}
+	return schedule
+}
+
+// next returns the func of the run date following the previous one
+func (s Schedule) next() (func(time.Time) time.Time, error) {
+	if s.Cron == "" {
+		return func(previous time.Time) time.Time {
+			return previous.Add(s.Interval)
+		}, nil
+	}
+	expression, err := cronParser.Parse(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	return expression.Next, nil
+}
+
+// jitter returns a random delay up to the schedule jitter
+func (s Schedule) jitter() time.Duration {
+	if s.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.Jitter)))
+}
+
+// scheduled is the group item with its schedule and the first run date
+type scheduled struct {
+	item     Item
+	schedule Schedule
+	next     func(time.Time) time.Time
+	due      time.Time
+	err      error
+}
+
+// plan returns the group items with their first runs after now, spread shifts the first runs
+// evenly over the interval among the items sharing the schedule
+func (g ItemsGroup) plan(now time.Time) []scheduled {
+	result := make([]scheduled, 0, len(g.Items))
+	shared := make(map[Schedule]int)
+	for _, item := range g.Items {
+		schedule := g.scheduleOf(item)
+		shared[schedule]++
+		result = append(result, scheduled{item: item, schedule: schedule})
+	}
+	index := make(map[Schedule]int)
+	for i := range result {
+		planned := &result[i]
+		planned.next, planned.err = planned.schedule.next()
+		if planned.err != nil {
+			continue
+		}
+		var offset time.Duration
+		if planned.schedule.Spread && planned.schedule.Cron == "" {
+			offset = planned.schedule.Interval * time.Duration(index[planned.schedule]) / time.Duration(shared[planned.schedule])
+			index[planned.schedule]++
+		}
+		planned.due = planned.next(now).Add(offset)
+		if planned.schedule.Immediate {
+			planned.due = now.Add(offset)
+		}
+	}
+	return result
+}
+
+// following returns the first run after now following due, missed runs are skipped
+func following(next func(time.Time) time.Time, due, now time.Time) time.Time {
+	due = next(due)
+	for !due.IsZero() && !due.After(now) {
+		due = next(due)
+	}
+	return due
+}
+
+// Sender schedules every group item until ctx is done or the pinger is stopping
+func (d *Data) Sender(ctx context.Context, group ItemsGroup) {
+	wg := &sync.WaitGroup{}
+	for _, planned := range group.plan(time.Now()) {
+		if planned.err != nil {
+			d.logger.Error(ctx, planned.err, "item schedule", "item", planned.item.Key())
+			continue
+		}
+		wg.Add(1)
+		go func(planned scheduled) {
+			defer wg.Done()
+			defer d.recovered(ctx, "schedule")
+			d.schedule(ctx, planned.item, planned.schedule, planned.next, planned.due)
+		}(planned)
+	}
+	wg.Wait()
+}
+
+// schedule queues the item at every due date, missed dates are skipped so runs do not drift or pile up
+func (d *Data) schedule(ctx context.Context, item Item, schedule Schedule, next func(time.Time) time.Time, due time.Time) {
+	_, stopping := d.running()
+	for {
+		if due.IsZero() {
+			d.logger.Warn(ctx, "schedule has no next run", "item", item.Key())
+			return
+		}
+		timer := time.NewTimer(time.Until(due) + schedule.jitter())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-stopping:
+			timer.Stop()
+			return
+		case <-timer.C:
+			d.logger.Debug(ctx, "send by schedule", "item", item.Key())
+			d.Send(ctx, []Item{item})
+		}
+		due = following(next, due, time.Now())
+	}
+}
+
+func (s Schedule) validate(path string, errs *ValidationErrors) {
+	if s.Interval != 0 && s.Cron != "" {
+		errs.add(path, "interval and cron are mutually exclusive")
+	}
+	if s.Interval < 0 {
+		errs.add(path+".interval", "must not be negative")
+	}
+	if s.Jitter < 0 {
+		errs.add(path+".jitter", "must not be negative")
+	}
+	if s.Cron != "" {
+		if _, err := cronParser.Parse(s.Cron); err != nil {
+			errs.add(path+".cron", "%s", err)
+		}
+		if s.Spread {
+			errs.add(path+".spread", "is supported by interval schedules only")
+		}
+	}
+}
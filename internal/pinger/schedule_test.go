@@ -0,0 +1,145 @@
+package pinger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleOf(t *testing.T) {
+	own := &Schedule{Interval: time.Minute, Jitter: time.Second}
+	shared := &Schedule{Cron: "*/5 * * * *"}
+	cases := []struct {
+		name  string
+		group ItemsGroup
+		item  Item
+		want  Schedule
+	}{
+		{"group timeout", ItemsGroup{Timeout: 30 * time.Second}, Item{}, Schedule{Interval: 30 * time.Second}},
+		{"group schedule", ItemsGroup{Timeout: 30 * time.Second, Schedule: shared}, Item{}, *shared},
+		{"item schedule", ItemsGroup{Timeout: 30 * time.Second, Schedule: shared}, Item{Schedule: own}, *own},
+		{"options only", ItemsGroup{Timeout: 30 * time.Second, Schedule: &Schedule{Spread: true}}, Item{}, Schedule{Interval: 30 * time.Second, Spread: true}},
+	}
+	for _, c := range cases {
+		if got := c.group.scheduleOf(c.item); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 2, 30, 0, time.UTC)
+	cases := []struct {
+		name     string
+		schedule Schedule
+		want     time.Time
+	}{
+		{"interval", Schedule{Interval: 90 * time.Second}, now.Add(90 * time.Second)},
+		{"cron", Schedule{Cron: "*/5 * * * *"}, time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC)},
+		{"cron with seconds", Schedule{Cron: "15 * * * * *"}, time.Date(2024, 1, 1, 10, 3, 15, 0, time.UTC)},
+		{"descriptor", Schedule{Cron: "@hourly"}, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		next, err := c.schedule.next()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got := next(now); !got.Equal(c.want) {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+	if _, err := (Schedule{Cron: "every minute"}).next(); err == nil {
+		t.Error("invalid cron: want an error")
+	}
+}
+
+func TestSchedulePlan(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	group := ItemsGroup{
+		Timeout:  time.Minute,
+		Schedule: &Schedule{Spread: true},
+		Items: []Item{
+			{Name: "a"},
+			{Name: "own", Schedule: &Schedule{Interval: 10 * time.Second}},
+			{Name: "b"},
+			{Name: "c"},
+			{Name: "cron", Schedule: &Schedule{Cron: "*/5 * * * *"}},
+			{Name: "invalid", Schedule: &Schedule{Cron: "never"}},
+		},
+	}
+	want := map[string]time.Time{
+		// spread over the three items of the group schedule only
+		"a":    now.Add(time.Minute),
+		"b":    now.Add(time.Minute + 20*time.Second),
+		"c":    now.Add(time.Minute + 40*time.Second),
+		"own":  now.Add(10 * time.Second),
+		"cron": now.Add(5 * time.Minute),
+	}
+	planned := group.plan(now)
+	if len(planned) != len(group.Items) {
+		t.Fatalf("%d planned items, want %d", len(planned), len(group.Items))
+	}
+	for _, item := range planned {
+		if item.item.Name == "invalid" {
+			if item.err == nil {
+				t.Error("invalid: want an error")
+			}
+			continue
+		}
+		if item.err != nil {
+			t.Errorf("%s: %v", item.item.Name, item.err)
+			continue
+		}
+		if !item.due.Equal(want[item.item.Name]) {
+			t.Errorf("%s: due %s, want %s", item.item.Name, item.due, want[item.item.Name])
+		}
+	}
+
+	group.Schedule = &Schedule{Spread: true, Immediate: true}
+	group.Items = []Item{{Name: "a"}, {Name: "b"}}
+	for i, item := range group.plan(now) {
+		if wantDue := now.Add(time.Duration(i) * 30 * time.Second); !item.due.Equal(wantDue) {
+			t.Errorf("immediate %s: due %s, want %s", item.item.Name, item.due, wantDue)
+		}
+	}
+}
+
+func TestScheduleSkipsMissedRuns(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	interval, err := Schedule{Interval: time.Minute}.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cron, err := Schedule{Cron: "*/5 * * * *"}.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		next func(time.Time) time.Time
+		due  time.Time
+		want time.Time
+	}{
+		{"on time", interval, now, now.Add(time.Minute)},
+		{"interval keeps the anchor", interval, now.Add(-150 * time.Second), now.Add(30 * time.Second)},
+		{"run at now is missed", interval, now.Add(-time.Minute), now.Add(time.Minute)},
+		{"cron", cron, now.Add(-17 * time.Minute), now.Add(5 * time.Minute)},
+	}
+	for _, c := range cases {
+		if got := following(c.next, c.due, now); !got.Equal(c.want) {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestScheduleJitter(t *testing.T) {
+	if jitter := (Schedule{}).jitter(); jitter != 0 {
+		t.Errorf("no jitter: got %s", jitter)
+	}
+	schedule := Schedule{Jitter: 10 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		if jitter := schedule.jitter(); jitter < 0 || jitter >= schedule.Jitter {
+			t.Fatalf("jitter %s out of [0, %s)", jitter, schedule.Jitter)
+		}
+	}
+}
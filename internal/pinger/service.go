@@ -2,17 +2,19 @@ package pinger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"runtime"
-	"strings"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	pinger "github.com/go-ping/ping"
+	"github.com/patrickmn/go-cache"
 
 	"observer/internal/domain/services"
 	"observer/internal/logger"
@@ -20,133 +22,422 @@ import (
 	"observer/pkg/mediator"
 )
 
-//TODO: ping history, count of ping, result history, trigger by good and bad result (+antispam -> notice for change status)
-
 const queueLimit = 10000
 
+// job is a queued item with the context of its group, the check is canceled with the group
+type job struct {
+	ctx  context.Context
+	item Item
+}
+
 type Data struct {
 	ItemsGroup []ItemsGroup `json:"items_group"`
+	configFile string
+	runCtx     context.Context
+	cancel     context.CancelFunc
+	stopping   chan struct{}
+	sending    *sync.WaitGroup
+	receiving  *sync.WaitGroup
+	triggering *sync.WaitGroup
+	senders    map[string]context.CancelFunc
 	dispatcher *mediator.Dispatcher
 	logger     *logger.Logger
 	settings   services.Settings
-	queue      chan Item
-	history    History
+	queue      chan job
+	triggers   []chan triggerCall
+	history    *History
+	notifier   *notifier
+	statuses   *statusStore
+	tokens     *cache.Cache
+	jars       map[string]http.CookieJar
+	panicked   error
 	mutex      *sync.Mutex
 }
 
-func New(dispatcher *mediator.Dispatcher, logger *logger.Logger, settings services.Settings) *Data {
+func New(dispatcher *mediator.Dispatcher, logger *logger.Logger, settings services.Settings, configFile string) *Data {
 	logger = logger.With("service", "pinger")
 	return &Data{
+		configFile: configFile,
 		dispatcher: dispatcher,
 		logger:     logger,
 		settings:   settings,
-		queue:      make(chan Item, queueLimit),
 		ItemsGroup: make([]ItemsGroup, 0),
-		history: History{
-			Requests: make(map[time.Time]Request),
-		},
-		mutex: &sync.Mutex{},
+		senders:    make(map[string]context.CancelFunc),
+		sending:    &sync.WaitGroup{},
+		receiving:  &sync.WaitGroup{},
+		triggering: &sync.WaitGroup{},
+		history: NewHistory(
+			settings.GetValueInt("OBSERVER_PINGER_HISTORY_LIMIT", 1000),
+			settings.GetValueHours("OBSERVER_PINGER_HISTORY_HOURS", 24),
+		),
+		notifier: newNotifier(),
+		statuses: newStatusStore(),
+		tokens:   cache.New(cache.NoExpiration, 10*time.Minute),
+		jars:     make(map[string]http.CookieJar),
+		mutex:    &sync.Mutex{},
 	}
 }
 
-func (d *Data) Append(t time.Duration, items ...Item) []ItemsGroup {
-	d.ItemsGroup = append(d.ItemsGroup, ItemsGroup{
-		Timeout: t,
-		Items:   items,
-	})
-	return d.ItemsGroup
+// Init checks the config and restores the saved state
+func (d *Data) Init(ctx context.Context) error {
+	if _, err := LoadConfig(d.configFile); err != nil {
+		return err
+	}
+	if err := d.loadState(); err != nil {
+		d.logger.Error(ctx, err, "load state, starting without history")
+	}
+	return nil
 }
 
-func (d *Data) Start(ctx context.Context) {
-	d.logger.Info(ctx, "Start Pinger")
-	d.Append(time.Minute*25,
-		PingItem("188.21.21.21", d.settings.GetValueSeconds("OBSERVER_PINGER_PING_TIMEOUT_SEC", 5), d.settings.GetValueInt("OBSERVER_PINGER_PING_REPEAT", 3)), //some not pinged
-		CheckStatusItem("https://google.com/"),                                   //domain exist, server state successful
-		CheckStatusItem("https://dima.com/"),                                     //domain exist, server state fail
-		CheckStatusItem("https://no-exist-domain-243524523452345234524524.com/"), //domain not exist, server state fail
-	)
-
-	d.Append(time.Second*55,
-		PingItem("127.0.0.2", d.settings.GetValueSeconds("OBSERVER_PINGER_PING_TIMEOUT_SEC", 5), d.settings.GetValueInt("OBSERVER_PINGER_PING_REPEAT", 3)), //some pinged
-	)
+// Start runs checks of the config items, checks are not canceled with ctx but by Stop
+func (d *Data) Start(ctx context.Context) error {
+	d.logger.Info(ctx, "Start Pinger", "config", d.configFile)
+	config, err := LoadConfig(d.configFile)
+	if err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	if d.runCtx != nil {
+		d.mutex.Unlock()
+		return errors.New("pinger is already running")
+	}
+	d.runCtx, d.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	d.queue = make(chan job, queueLimit)
+	d.stopping = make(chan struct{})
+	d.panicked = nil
+	d.triggers = d.startTriggers()
+	runCtx := d.runCtx
+	d.mutex.Unlock()
 	for i := 0; i < runtime.NumCPU(); i++ {
-		go d.Receiver(ctx)
+		d.receiving.Add(1)
+		go func() {
+			defer d.receiving.Done()
+			d.Receiver(ctx)
+		}()
 	}
-	go d.Sender(ctx)
+	d.apply(ctx, config)
+	go func() {
+		defer d.recovered(runCtx, "watch")
+		d.watch(runCtx)
+	}()
+	return nil
 }
 
-func (d *Data) Sender(ctx context.Context) {
-	for _, ig := range d.ItemsGroup {
-		igCopy := ig
-		go func() {
-			for {
-				select {
-				case <-time.After(igCopy.Timeout): //d.settings.AfterSeconds("OBSERVER_PINGER_SENDER_TIMEOUT_SEC", 15):
-					println("")
-					d.logger.Info(ctx, "send by timeout")
-					d.Send(ctx, igCopy.Items)
+// Health returns an error when the pinger is not running, its goroutine panicked or its queue is full
+func (d *Data) Health(context.Context) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.runCtx == nil {
+		return errors.New("pinger is not running")
+	}
+	if d.panicked != nil {
+		return d.panicked
+	}
+	if len(d.queue) == cap(d.queue) {
+		return fmt.Errorf("queue is full, %d items", len(d.queue))
+	}
+	return nil
+}
+
+// withDefaults fills the request values omitted in config from the group and settings
+func (d *Data) withDefaults(group ItemsGroup) []Item {
+	result := make([]Item, 0, len(group.Items))
+	for _, item := range group.Items {
+		if item.Request.Timeout == 0 {
+			item.Request.Timeout = group.RequestTimeout
+		}
+		switch item.Kind() {
+		case KindWeb:
+			if item.Request.Timeout == 0 {
+				item.Request.Timeout = d.webTimeout()
+			}
+			if item.Request.Proxy == nil && len(item.Request.Proxies) == 0 {
+				item.Request.Proxy = group.Proxy
+			}
+			result = append(result, item.viaProxies()...)
+			continue
+		case KindSteps:
+			steps := make([]Step, 0, len(item.Steps))
+			for _, step := range item.Steps {
+				step.Request.Timeout = defaults.Dec(step.Request.Timeout, defaults.Dec(group.RequestTimeout, d.webTimeout()))
+				if step.Request.Proxy == nil {
+					step.Request.Proxy = group.Proxy
 				}
+				steps = append(steps, step)
 			}
-		}()
+			item.Steps = steps
+		case KindPing:
+			if item.Request.Timeout == 0 {
+				item.Request.Timeout = d.settings.GetValueSeconds("OBSERVER_PINGER_PING_TIMEOUT_SEC", 5)
+			}
+			if item.Request.Repeat == 0 {
+				item.Request.Repeat = d.settings.GetValueInt("OBSERVER_PINGER_PING_REPEAT", 3)
+			}
+		case KindTcp:
+			if item.Request.Timeout == 0 {
+				item.Request.Timeout = d.settings.GetValueSeconds("OBSERVER_PINGER_TCP_TIMEOUT_SEC", 5)
+			}
+		case KindDns:
+			if item.Request.Timeout == 0 {
+				item.Request.Timeout = d.settings.GetValueSeconds("OBSERVER_PINGER_DNS_TIMEOUT_SEC", 5)
+			}
+		case KindCert:
+			if item.Request.Timeout == 0 {
+				item.Request.Timeout = d.settings.GetValueSeconds("OBSERVER_PINGER_TLS_TIMEOUT_SEC", 10)
+			}
+			if item.Request.Certificate.WarningDays == 0 {
+				item.Request.Certificate.WarningDays = d.settings.GetValueInt("OBSERVER_PINGER_TLS_WARNING_DAYS", 30)
+			}
+			if item.Request.Certificate.CriticalDays == 0 {
+				item.Request.Certificate.CriticalDays = d.settings.GetValueInt("OBSERVER_PINGER_TLS_CRITICAL_DAYS", 7)
+			}
+		case KindGrpc:
+			if item.Request.Timeout == 0 {
+				item.Request.Timeout = d.settings.GetValueSeconds("OBSERVER_PINGER_GRPC_TIMEOUT_SEC", 5)
+			}
+		}
+		result = append(result, item)
 	}
+	return result
 }
 
+func (d *Data) webTimeout() time.Duration {
+	return d.settings.GetValueSeconds("OBSERVER_PINGER_WEB_TIMEOUT_SEC", 30)
+}
+
+// Send queues items until ctx is done or the pinger is stopping
 func (d *Data) Send(ctx context.Context, items []Item) {
+	queue, stopping := d.running()
 	for _, item := range items {
 		d.logger.Debug(ctx, "sending item", "item", item)
-		d.queue <- item
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopping:
+			return
+		case queue <- job{ctx: ctx, item: item}:
+		}
 	}
 }
 
-func (d *Data) Receiver(ctx context.Context) {
-	for item := range d.queue {
-		d.logger.Info(ctx, "receiving item", "Name", item.Name)
-		if item.Request.Ping != "" {
-			state, err := d.ping(item.Request.Ping,
-				item.Request.Repeat,
-				item.Request.Timeout)
-			d.logger.Info(ctx, fmt.Sprintf("Received [%s] ping for url [%s] result %s",
-				defaults.Str(item.Name, item.Request.Url),
-				item.Request.Url,
-				fmt.Sprintf("%v err: %v", state, err),
-			))
-			continue
+// running returns the queue and the stopping channel of the current run
+func (d *Data) running() (chan job, chan struct{}) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.queue, d.stopping
+}
+
+// Stop stops scheduling, waits for the queued and running checks and triggers until ctx is done
+// and saves the state, checks left at the deadline are canceled
+func (d *Data) Stop(ctx context.Context) error {
+	d.mutex.Lock()
+	if d.runCtx == nil {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.runCtx = nil
+	d.senders = make(map[string]context.CancelFunc)
+	close(d.stopping)
+	queue, triggers, cancel := d.queue, d.triggers, d.cancel
+	d.mutex.Unlock()
+	d.logger.Info(ctx, "stop pinger", "queued", len(queue))
+	d.sending.Wait()
+	close(queue)
+	drained := make(chan struct{})
+	go func() {
+		d.receiving.Wait()
+		for _, trigger := range triggers {
+			close(trigger)
 		}
-		host := getHost(item.Request.Url)
-		if host != "" {
-			result := d.web(ctx, item)
-			d.logger.Info(ctx, fmt.Sprintf("Received [%s] web for url [%s] result %s",
-				defaults.Str(item.Name, item.Request.Url),
-				item.Request.Url,
-				fmt.Sprintf("%v err: %v", result.StatusCode, result.Error),
-			))
-			continue
-		} else {
-			d.logger.Info(ctx, fmt.Sprintf("EMPTY HOST [%s] is empty", item.Request.Url), "item", item)
+		d.triggering.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("pinger stop: %w", ctx.Err())
+	}
+	cancel()
+	return errors.Join(err, d.saveState())
+}
+
+// Receiver checks the queued items, results of checks canceled by reload or stop are dropped
+func (d *Data) Receiver(ctx context.Context) {
+	queue, _ := d.running()
+	for job := range queue {
+		d.receive(ctx, job)
+	}
+}
+
+// receive checks the job item and records the result, a panic of the check is reported
+// and the worker goes on with the next job
+func (d *Data) receive(ctx context.Context, job job) {
+	defer d.recovered(ctx, "receiver")
+	item := job.item
+	if job.ctx.Err() != nil {
+		return
+	}
+	d.logger.Info(ctx, "receiving item", "Name", item.Name)
+	kind := item.Kind()
+	if kind == "" {
+		d.logger.Info(ctx, fmt.Sprintf("EMPTY HOST [%s] is empty", item.Request.Url), "item", item)
+		return
+	}
+	result := d.checkWithRetry(job.ctx, item)
+	if job.ctx.Err() != nil {
+		d.logger.Info(ctx, "check canceled", "item", item.Key())
+		return
+	}
+	d.record(item, result)
+	d.trigger(job.ctx, item, result)
+	d.logger.Info(ctx, fmt.Sprintf("Received [%s] %s for [%s] result %s",
+		item.Key(),
+		kind,
+		item.Request.Target(),
+		fmt.Sprintf("%v code: %v err: %v", result.State(), result.StatusCode, defaults.Str(result.Error, result.Warning)),
+	))
+}
+
+// recovered reports a panic of the pinger goroutine, it is deferred by the goroutine itself,
+// Health fails until the pinger is restarted
+func (d *Data) recovered(ctx context.Context, name string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	err := fmt.Errorf("%s panic: %v", name, recovered)
+	d.logger.Error(ctx, err, "pinger goroutine panic", "stack", string(debug.Stack()))
+	d.mutex.Lock()
+	d.panicked = err
+	d.mutex.Unlock()
+}
+
+// check runs the item request by its kind
+func (d *Data) check(ctx context.Context, item Item) ResponseResult {
+	var result ResponseResult
+	start := time.Now()
+	switch item.Kind() {
+	case KindPing:
+		result = d.ping(ctx, item.Request)
+	case KindTcp:
+		result = d.tcp(ctx, item.Request)
+	case KindDns:
+		result = d.dns(ctx, item.Request)
+	case KindCert:
+		result = d.certificate(ctx, item.Request)
+	case KindGrpc:
+		result = d.grpcHealth(ctx, item.Request)
+	case KindSteps:
+		result = d.steps(ctx, item)
+	case KindWeb:
+		result = d.web(ctx, item)
+	default:
+		result = result.SetErr("nothing to check")
+	}
+	result.Date = start
+	if result.Latency == 0 {
+		result.Latency = time.Since(start)
+	}
+	return result
+}
+
+func (d *Data) record(item Item, result ResponseResult) {
+	d.statuses.Update(item.Key(), result)
+	d.history.Add(item.Key(), HistoryRecord{
+		ItemId:     item.Id,
+		ItemName:   item.Name,
+		EventDate:  result.Date,
+		Latency:    result.Latency,
+		StatusCode: result.StatusCode,
+		Error:      result.Error,
+		Successful: result.Successful,
+		ErrorKind:  result.ErrorKind,
+		State:      result.State(),
+		Ping:       result.Ping,
+	})
+}
+
+// History returns the item results within [from, to], zero bounds are ignored
+func (d *Data) History(key string, from, to time.Time) []HistoryRecord {
+	return d.history.Get(key, from, to)
+}
+
+// Status returns the live status of the item
+func (d *Data) Status(key string) (Status, bool) {
+	return d.statuses.Get(key)
+}
+
+// Statuses returns the live status of all checked items by item key
+func (d *Data) Statuses() map[string]Status {
+	return d.statuses.All()
+}
+
+// Items returns the observed items with the live status filled
+func (d *Data) Items() []Item {
+	d.mutex.Lock()
+	groups := d.ItemsGroup
+	d.mutex.Unlock()
+	items := make([]Item, 0)
+	for _, group := range groups {
+		for _, item := range group.Items {
+			if status, ok := d.statuses.Get(item.Key()); ok {
+				item.Status = status
+			}
+			items = append(items, item)
 		}
 	}
+	return items
 }
 
-func (d *Data) ping(address string, repeat int, timeout time.Duration) (bool, error) {
-	pinger, err := pinger.NewPinger(address)
+// ping sends the packets and checks loss and average rtt by the limits:
+// above the fail limits the item is down, above the degraded ones it is up with a warning
+func (d *Data) ping(ctx context.Context, request Request) ResponseResult {
+	result := ResponseResult{}
+	pinger, err := pinger.NewPinger(request.Ping)
 	if err != nil {
-		return false, err
+		return result.WithErr("ping err: %s", err)
 	}
-	pinger.Count = repeat
-	pinger.Timeout = timeout
+	pinger.Count = request.Repeat
+	pinger.Timeout = request.Timeout
+	pinger.SetPrivileged(request.Privileged)
+	stop := context.AfterFunc(ctx, pinger.Stop)
+	defer stop()
 	err = pinger.Run()
 	if err != nil {
-		return false, err
+		return result.WithErr("ping err: %s", err)
 	}
 	stats := pinger.Statistics() // get send/receive/rtt stats
 	if stats == nil {
-		return false, nil
+		return result.SetErr("empty ping statistics")
+	}
+	result.Latency = stats.AvgRtt
+	result.Ping = &PingResult{
+		Sent:     stats.PacketsSent,
+		Received: stats.PacketsRecv,
+		Loss:     stats.PacketLoss,
+		Min:      stats.MinRtt,
+		Avg:      stats.AvgRtt,
+		Max:      stats.MaxRtt,
+		StdDev:   stats.StdDevRtt,
+	}
+	result.Body = fmt.Sprintf("%d/%d packets, %.1f%% loss, rtt min/avg/max/stddev %s/%s/%s/%s",
+		stats.PacketsRecv, stats.PacketsSent, stats.PacketLoss, stats.MinRtt, stats.AvgRtt, stats.MaxRtt, stats.StdDevRtt)
+	limits := PingLimits{}
+	if request.Response.Ping != nil {
+		limits = *request.Response.Ping
 	}
-	if stats.PacketsRecv != repeat {
-		return false, nil
+	limits = limits.withFailLoss(float64(d.settings.GetValueInt("OBSERVER_PINGER_PING_FAIL_LOSS", 50)))
+	result.Assertions = limits.assert(result.Ping, false)
+	if failed := failedAssertions(result.Assertions); failed != "" {
+		return result.SetErr(failed)
+	}
+	if degraded := failedAssertions(limits.assert(result.Ping, true)); degraded != "" {
+		result.Warning = "degraded: " + degraded
 	}
 	//d.logger.Info(context.Background(), "ping address", "address", address, "stats", pinger.Statistics())
-	return true, err
+	result.Successful = true
+	return result
 }
 
 func getHost(address string) string {
@@ -165,61 +456,64 @@ func getHost(address string) string {
 }
 
 func (d *Data) web(ctx context.Context, item Item) ResponseResult {
+	jar, err := d.cookieJar(item)
+	if err != nil {
+		return ResponseResult{}.WithErr("cookie jar err: %s", err)
+	}
+	result, _ := d.webExchange(ctx, item, jar)
+	return result
+}
+
+// webExchange sends the item request using the cookie jar and checks the response,
+// the response body is read and closed
+func (d *Data) webExchange(ctx context.Context, item Item, jar http.CookieJar) (ResponseResult, *http.Response) {
 	result := ResponseResult{}
-	client := &http.Client{}
+	transport, err := item.Request.transport()
+	if err != nil {
+		return result.WithErr("transport err: %s", err), nil
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Jar: jar, Transport: transport, Timeout: item.Request.Timeout, CheckRedirect: item.Request.Redirect.policy()}
+	request, err := item.buildRequest(ctx)
+	if err != nil {
+		return result.WithErr("build request err: %s", err), nil
+	}
 	if item.Request.Proxy != nil {
-		proxyURL, err := url.Parse(item.Request.Proxy.Host)
-		if err != nil {
-			return result.WithErr("Parse proxy url err: %s", err)
-		}
-		if item.Request.Proxy.User != "" {
-			//proxyURL.Host = address
-			proxyURL.User = url.UserPassword(item.Request.Proxy.User, item.Request.Proxy.Pass)
+		if err = item.Request.Proxy.authorize(request); err != nil {
+			return result.WithErr("proxy err: %s", err), nil
 		}
-		proxy := http.ProxyURL(proxyURL)
-		transport := &http.Transport{Proxy: proxy}
-		client.Transport = transport
-		//clientWithProxy, err := transport.DialContext(ctx, "tcp", item.Request.Proxy.Host)
-		//if nil != err {
-		//	log.Fatalf("Dial: %v", err)
-		//}
-		//client = &clientWithProxy
-	}
-	request, err := item.buildRequest()
-	if err != nil {
-		return result.WithErr("build request err: %s", err)
 	}
+	if err = d.authorize(ctx, request, item.Request.Auth); err != nil {
+		return result.WithErr("auth err: %s", err), nil
+	}
+	request, trace := traceRequest(request)
 	resp, err := client.Do(request)
 	if err != nil {
-		return result.WithErr("request err: %s", err)
+		result.Timing = trace.Timing(time.Now())
+		return result.WithErr("request err: %s", err), nil
 	}
 	if resp == nil {
-		return result.SetErr("empty response")
-	}
-	if item.Request.Response.Status.Code != 0 && resp.StatusCode == item.Request.Response.Status.Code {
-		return result
-	}
-	if item.Request.Response.Status.Min != 0 && item.Request.Response.Status.Max != 0 &&
-		resp.StatusCode >= item.Request.Response.Status.Min && resp.StatusCode <= item.Request.Response.Status.Max {
-		return result
+		return result.SetErr("empty response"), nil
 	}
+	defer resp.Body.Close()
 	result.StatusCode = resp.StatusCode
+	result.FinalUrl = resp.Request.URL.String()
+	result.Redirects = redirects(resp)
 	webBody, err := io.ReadAll(resp.Body)
+	result.Timing = trace.Timing(time.Now())
+	result.Latency = result.Timing.Total
 	if err != nil {
-		return result.WithErr("read body err: %s", err)
+		return result.WithErr("read body err: %s", err), resp
 	}
 	result.Body = string(webBody)
-	if item.Request.Response.Body != nil {
-		if item.Request.Response.Body.Full == result.Body {
-			return result
-		}
-		if strings.Contains(result.Body, item.Request.Response.Body.Contain) {
-			return result
-		}
-		if item.Request.Response.Body.Grep != nil {
-			//TODO: grep
-			return result
-		}
+	result.Assertions = item.Request.Response.Assert(resp.StatusCode, resp.Header.Get("Content-Type"), result.Body)
+	if item.Request.Response.Timing != nil {
+		result.Assertions = append(result.Assertions, item.Request.Response.Timing.assert(result.Timing)...)
 	}
-	return result
+	result.Assertions = append(result.Assertions, item.Request.Response.assertRedirect(result.FinalUrl, resp.Header.Get("Location"))...)
+	if failed := failedAssertions(result.Assertions); failed != "" {
+		return result.SetErr(failed), resp
+	}
+	result.Successful = true
+	return result, resp
 }
@@ -0,0 +1,30 @@
+package pinger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceivePanicIsReported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	d := testData()
+	ctx := context.Background()
+	d.runCtx = ctx
+	d.queue = make(chan job, 1)
+	if err := d.Health(ctx); err != nil {
+		t.Fatalf("health before panic: %v", err)
+	}
+	// a nil status store panics on record
+	d.statuses = nil
+	item := Item{Name: "web", Request: Request{Url: server.URL, Timeout: time.Second}}
+	d.receive(ctx, job{ctx: ctx, item: item})
+	if err := d.Health(ctx); err == nil || !strings.Contains(err.Error(), "receiver panic") {
+		t.Errorf("health %v, want the receiver panic", err)
+	}
+}
@@ -0,0 +1,81 @@
+package pinger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// state is the statuses and history saved on shutdown and restored on start
+type state struct {
+	Statuses map[string]Status          `json:"statuses"`
+	History  map[string][]HistoryRecord `json:"history"`
+}
+
+func (d *Data) stateFile() string {
+	return d.settings.GetValue("OBSERVER_PINGER_STATE_FILE", "")
+}
+
+// saveState writes the state file atomically, nothing is saved without the file setting
+func (d *Data) saveState() error {
+	path := d.stateFile()
+	if path == "" {
+		return nil
+	}
+	snapshot := state{
+		Statuses: d.statuses.All(),
+		History:  make(map[string][]HistoryRecord),
+	}
+	for _, key := range d.history.Keys() {
+		snapshot.History[key] = d.history.Get(key, time.Time{}, time.Time{})
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err = temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("save state: %w", err)
+	}
+	if err = temp.Close(); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	if err = os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("save state: %w", err)
+	}
+	return nil
+}
+
+// loadState restores statuses and history from the state file, a missing file is not an error
+func (d *Data) loadState() error {
+	path := d.stateFile()
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	snapshot := state{}
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("load state %s: %w", path, err)
+	}
+	d.statuses.restore(snapshot.Statuses)
+	for key, records := range snapshot.History {
+		for _, record := range records {
+			d.history.Add(key, record)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package pinger
+
+import (
+	"sync"
+)
+
+// statusStore keeps the live status of every item by item key
+type statusStore struct {
+	items map[string]Status
+	mutex *sync.RWMutex
+}
+
+func newStatusStore() *statusStore {
+	return &statusStore{
+		items: make(map[string]Status),
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// Update applies the check result to the item status and returns the new status
+func (s *statusStore) Update(key string, result ResponseResult) Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status := s.items[key]
+	name := result.State()
+	if status.Name != name {
+		status.Name = name
+		status.Since = result.Date
+	}
+	if result.Successful {
+		status.ConsecutiveSuccess++
+		status.ConsecutiveFailure = 0
+	} else {
+		status.ConsecutiveFailure++
+		status.ConsecutiveSuccess = 0
+	}
+	status.EventsCount++
+	status.LastEventDate = result.Date
+	status.LastCode = result.StatusCode
+	status.LastError = result.Error
+	s.items[key] = status
+	return status
+}
+
+func (s *statusStore) Get(key string) (Status, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	status, ok := s.items[key]
+	return status, ok
+}
+
+func (s *statusStore) All() map[string]Status {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	result := make(map[string]Status, len(s.items))
+	for key, status := range s.items {
+		result[key] = status
+	}
+	return result
+}
+
+// restore sets the saved statuses, used to continue after restart
+func (s *statusStore) restore(items map[string]Status) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, status := range items {
+		s.items[key] = status
+	}
+}
+
+// prune drops the statuses of items missing in keys
+func (s *statusStore) prune(keys map[string]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key := range s.items {
+		if !keys[key] {
+			delete(s.items, key)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package pinger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// steps runs the item web requests in order, values extracted from a response
+// are rendered into url, header and body templates of the later steps
+func (d *Data) steps(ctx context.Context, item Item) ResponseResult {
+	result := ResponseResult{}
+	jar, err := d.cookieJar(item)
+	if err != nil {
+		return result.WithErr("cookie jar err: %s", err)
+	}
+	vars := make(map[string]string)
+	for i, step := range item.Steps {
+		name := step.name(i)
+		request, err := step.Request.render(vars)
+		if err != nil {
+			return result.SetErr(fmt.Sprintf("%s render err: %s", name, err))
+		}
+		stepResult, response := d.webExchange(ctx, Item{Name: name, Request: request}, jar)
+		result.StatusCode = stepResult.StatusCode
+		result.Body = stepResult.Body
+		result.Latency += stepResult.Latency
+		for _, assertion := range stepResult.Assertions {
+			assertion.Name = name + "." + assertion.Name
+			result.Assertions = append(result.Assertions, assertion)
+		}
+		if !stepResult.Successful {
+			return result.SetErr(fmt.Sprintf("%s: %s", name, stepResult.Error))
+		}
+		for _, extract := range step.Extract {
+			value, err := extract.value(response, stepResult.Body)
+			if err != nil {
+				return result.SetErr(fmt.Sprintf("%s extract %s err: %s", name, extract.Name, err))
+			}
+			vars[extract.Name] = value
+		}
+	}
+	result.Successful = true
+	return result
+}
+
+func (s Step) name(index int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("step[%d]", index)
+}
+
+// value takes the variable from the response by the configured source,
+// regex returns the first group when the expression has groups
+func (e Extract) value(response *http.Response, body string) (string, error) {
+	switch {
+	case e.JsonPath != "":
+		values, err := grepJsonPath(e.JsonPath, body)
+		if err != nil {
+			return "", err
+		}
+		if len(values) == 0 {
+			return "", fmt.Errorf("nothing found by %s", e.JsonPath)
+		}
+		return values[0], nil
+	case e.Regex != "":
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return "", err
+		}
+		match := re.FindStringSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("nothing found by %s", e.Regex)
+		}
+		if len(match) > 1 {
+			return match[1], nil
+		}
+		return match[0], nil
+	case e.Header != "":
+		if value := response.Header.Get(e.Header); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("header %s not found", e.Header)
+	case e.Cookie != "":
+		for _, cookie := range response.Cookies() {
+			if cookie.Name == e.Cookie && (cookie.Expires.IsZero() || cookie.Expires.After(time.Now())) {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("cookie %s not found", e.Cookie)
+	}
+	return "", fmt.Errorf("extract source is not set")
+}
@@ -0,0 +1,77 @@
+package pinger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tcpReadLimit = 64 * 1024
+
+// tcp connects to the address, sends the payload and waits for the expected banner substring
+func (d *Data) tcp(ctx context.Context, request Request) ResponseResult {
+	result := ResponseResult{}
+	dialer := &net.Dialer{Timeout: request.Timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", request.Tcp.Address)
+	if err != nil {
+		return result.WithErr("connect err: %s", err)
+	}
+	defer conn.Close()
+	result.Latency = time.Since(start)
+	if request.Timeout > 0 {
+		if err = conn.SetDeadline(time.Now().Add(request.Timeout)); err != nil {
+			return result.WithErr("set deadline err: %s", err)
+		}
+	}
+	if request.Tcp.Send != "" {
+		if _, err = conn.Write([]byte(request.Tcp.Send)); err != nil {
+			return result.WithErr("send err: %s", err)
+		}
+	}
+	if request.Tcp.Expect == "" {
+		result.Successful = true
+		return result
+	}
+	banner, err := readUntil(conn, request.Tcp.Expect)
+	result.Body = banner
+	assertion := AssertionResult{
+		Name:     "tcp.expect",
+		Expected: strconv.Quote(request.Tcp.Expect),
+		Actual:   strconv.Quote(shorten(banner)),
+		Passed:   strings.Contains(banner, request.Tcp.Expect),
+	}
+	result.Assertions = []AssertionResult{assertion}
+	if !assertion.Passed {
+		if err != nil {
+			return result.WithErr("read err: %s", err)
+		}
+		return result.SetErr(assertion.String())
+	}
+	result.Successful = true
+	return result
+}
+
+// readUntil reads from conn until the expected substring, EOF, deadline or the read limit
+func readUntil(conn net.Conn, expected string) (string, error) {
+	received := make([]byte, 0, 512)
+	buffer := make([]byte, 512)
+	for len(received) < tcpReadLimit {
+		n, err := conn.Read(buffer)
+		received = append(received, buffer[:n]...)
+		if strings.Contains(string(received), expected) {
+			return string(received), nil
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && len(received) > 0 {
+				return string(received), nil
+			}
+			return string(received), err
+		}
+	}
+	return string(received), nil
+}
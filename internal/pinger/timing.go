@@ -0,0 +1,118 @@
+package pinger
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// timingTrace collects the request phases dates by httptrace hooks
+type timingTrace struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	mutex        *sync.Mutex
+}
+
+// traceRequest attaches the timing trace to the request, connection phases are of the final redirect hop
+func traceRequest(request *http.Request) (*http.Request, *timingTrace) {
+	trace := &timingTrace{start: time.Now(), mutex: &sync.Mutex{}}
+	clientTrace := &httptrace.ClientTrace{
+		GetConn:  func(string) { trace.resetHop() },
+		DNSStart: func(httptrace.DNSStartInfo) { trace.set(&trace.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { trace.set(&trace.dnsDone) },
+		ConnectStart: func(string, string) {
+			trace.setOnce(&trace.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			trace.set(&trace.connectDone)
+		},
+		TLSHandshakeStart:    func() { trace.set(&trace.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { trace.set(&trace.tlsDone) },
+		GotFirstResponseByte: func() { trace.set(&trace.firstByte) },
+	}
+	return request.WithContext(httptrace.WithClientTrace(request.Context(), clientTrace)), trace
+}
+
+func (t *timingTrace) set(date *time.Time) {
+	t.mutex.Lock()
+	*date = time.Now()
+	t.mutex.Unlock()
+}
+
+// resetHop clears the connection phases of the previous redirect hop
+func (t *timingTrace) resetHop() {
+	t.mutex.Lock()
+	t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+	t.connectStart, t.connectDone = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+	t.mutex.Unlock()
+}
+
+// setOnce keeps the first date of the hop, dual stack dialing may start several connects
+func (t *timingTrace) setOnce(date *time.Time) {
+	t.mutex.Lock()
+	if date.IsZero() {
+		*date = time.Now()
+	}
+	t.mutex.Unlock()
+}
+
+// Timing returns phase durations, done is the date the body was read
+func (t *timingTrace) Timing(done time.Time) *HttpTiming {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	timing := &HttpTiming{
+		DnsLookup:    since(t.dnsStart, t.dnsDone),
+		TcpConnect:   since(t.connectStart, t.connectDone),
+		TlsHandshake: since(t.tlsStart, t.tlsDone),
+		FirstByte:    since(t.start, t.firstByte),
+		Total:        since(t.start, done),
+	}
+	if !t.firstByte.IsZero() {
+		timing.ContentTransfer = since(t.firstByte, done)
+	}
+	return timing
+}
+
+func since(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// assert compares measured phases with the limits, zero limits are ignored
+func (limits HttpTiming) assert(timing *HttpTiming) []AssertionResult {
+	assertions := make([]AssertionResult, 0)
+	for _, phase := range []struct {
+		name   string
+		limit  time.Duration
+		actual time.Duration
+	}{
+		{"timing.dns_lookup", limits.DnsLookup, timing.DnsLookup},
+		{"timing.tcp_connect", limits.TcpConnect, timing.TcpConnect},
+		{"timing.tls_handshake", limits.TlsHandshake, timing.TlsHandshake},
+		{"timing.first_byte", limits.FirstByte, timing.FirstByte},
+		{"timing.content_transfer", limits.ContentTransfer, timing.ContentTransfer},
+		{"timing.total", limits.Total, timing.Total},
+	} {
+		if phase.limit <= 0 {
+			continue
+		}
+		assertions = append(assertions, AssertionResult{
+			Name:     phase.name,
+			Expected: "<= " + phase.limit.String(),
+			Actual:   phase.actual.String(),
+			Passed:   phase.actual <= phase.limit,
+		})
+	}
+	return assertions
+}
@@ -0,0 +1,62 @@
+package pinger
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimingOfFinalRedirectHop(t *testing.T) {
+	final := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer final.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		http.Redirect(w, r, final.URL, http.StatusFound)
+	}))
+	defer slow.Close()
+
+	request, err := http.NewRequest(http.MethodGet, slow.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, trace := traceRequest(request)
+	client := &http.Client{Transport: &http.Transport{}}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	timing := trace.Timing(time.Now())
+
+	if timing.TcpConnect <= 0 || timing.TcpConnect >= 100*time.Millisecond {
+		t.Errorf("tcp connect %s, want the final hop connect only", timing.TcpConnect)
+	}
+	if timing.Total < 200*time.Millisecond {
+		t.Errorf("total %s, want the whole request", timing.Total)
+	}
+}
+
+func TestTimingOfEveryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	d := &Data{}
+	item := Item{Name: "web", Request: Request{Url: server.URL, Method: http.MethodGet, Timeout: time.Second}}
+	for run := 1; run <= 2; run++ {
+		result := d.web(context.Background(), item)
+		if !result.Successful {
+			t.Fatalf("run %d: %s", run, result.Error)
+		}
+		if result.Timing.TcpConnect <= 0 {
+			t.Errorf("run %d: tcp connect %s, want a new connection", run, result.Timing.TcpConnect)
+		}
+	}
+}
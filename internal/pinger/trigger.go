@@ -0,0 +1,199 @@
+package pinger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"text/template"
+	"time"
+)
+
+// triggerQueueLimit is the number of trigger calls waiting for a trigger worker
+const triggerQueueLimit = 100
+
+// triggerCall is the trigger request queued with the context of the check
+type triggerCall struct {
+	ctx     context.Context
+	name    string
+	request Request
+	data    TriggerData
+}
+
+// TriggerData is the check result available in trigger templates, e.g. {{.Name}} or {{json .Error}}
+type TriggerData struct {
+	Id         interface{}   `json:"id"`
+	Key        string        `json:"key"`
+	Name       string        `json:"name"`
+	Target     string        `json:"target"`
+	Successful bool          `json:"successful"`
+	State      string        `json:"state"`
+	StatusCode int           `json:"status_code"`
+	Error      string        `json:"error"`
+	ErrorKind  string        `json:"error_kind"`
+	Latency    time.Duration `json:"latency"`
+	LatencyMs  int64         `json:"latency_ms"`
+	Date       time.Time     `json:"date"`
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func newTriggerData(item Item, result ResponseResult) TriggerData {
+	return TriggerData{
+		Id:         item.Id,
+		Key:        item.Key(),
+		Name:       item.Name,
+		Target:     item.Request.Target(),
+		Successful: result.Successful,
+		State:      result.State(),
+		StatusCode: result.StatusCode,
+		Error:      result.Error,
+		ErrorKind:  result.ErrorKind,
+		Latency:    result.Latency,
+		LatencyMs:  result.Latency.Milliseconds(),
+		Date:       result.Date,
+	}
+}
+
+// trigger queues the follow-up requests configured for the item when its state is changed,
+// calls are sent by trigger workers so slow webhooks do not hold the check workers
+func (d *Data) trigger(ctx context.Context, item Item, result ResponseResult) {
+	trigger := item.Request.Trigger
+	if trigger == nil {
+		return
+	}
+	if !d.notifier.Transition(item.Key(), *trigger, result.Successful, result.Date) {
+		return
+	}
+	data := newTriggerData(item, result)
+	if result.Successful && trigger.OnSuccessful != nil {
+		d.queueTrigger(triggerCall{ctx: ctx, name: "on_successful", request: *trigger.OnSuccessful, data: data})
+	}
+	if !result.Successful && trigger.OnFail != nil {
+		d.queueTrigger(triggerCall{ctx: ctx, name: "on_fail", request: *trigger.OnFail, data: data})
+	}
+	if trigger.Always != nil {
+		d.queueTrigger(triggerCall{ctx: ctx, name: "always", request: *trigger.Always, data: data})
+	}
+}
+
+// queueTrigger passes the call to the worker of the item, so calls of an item keep their order,
+// the call is dropped when the worker queue is full
+func (d *Data) queueTrigger(call triggerCall) {
+	d.mutex.Lock()
+	triggers := d.triggers
+	d.mutex.Unlock()
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(call.data.Key))
+	select {
+	case triggers[hash.Sum32()%uint32(len(triggers))] <- call:
+	default:
+		d.logger.Warn(call.ctx, "trigger queue is full, call dropped", "item", call.data.Key, "trigger", call.name)
+	}
+}
+
+// triggerWorker sends the queued trigger calls until the queue is closed
+func (d *Data) triggerWorker(queue chan triggerCall) {
+	for call := range queue {
+		if call.ctx.Err() != nil {
+			continue
+		}
+		d.callTrigger(call.ctx, call.name, call.request, call.data)
+	}
+}
+
+// startTriggers starts the trigger workers, the number is OBSERVER_PINGER_TRIGGER_WORKERS
+func (d *Data) startTriggers() []chan triggerCall {
+	workers := d.settings.GetValueInt("OBSERVER_PINGER_TRIGGER_WORKERS", 4)
+	if workers < 1 {
+		workers = 1
+	}
+	triggers := make([]chan triggerCall, 0, workers)
+	for i := 0; i < workers; i++ {
+		queue := make(chan triggerCall, triggerQueueLimit)
+		triggers = append(triggers, queue)
+		d.triggering.Add(1)
+		go func() {
+			defer d.triggering.Done()
+			d.triggerWorker(queue)
+		}()
+	}
+	return triggers
+}
+
+func (d *Data) callTrigger(ctx context.Context, name string, request Request, data TriggerData) {
+	defer d.recovered(ctx, "trigger")
+	request, err := request.render(data)
+	if err != nil {
+		d.logger.Error(ctx, err, "render trigger request", "item", data.Key, "trigger", name)
+		return
+	}
+	request.Trigger = nil
+	if request.Timeout == 0 {
+		request.Timeout = d.webTimeout()
+	}
+	result := d.web(ctx, Item{Name: data.Key + "." + name, Request: request})
+	if result.Error != "" {
+		d.logger.Warn(ctx, "trigger request failed", "item", data.Key, "trigger", name, "error", result.Error)
+		return
+	}
+	d.logger.Info(ctx, "trigger request sent", "item", data.Key, "trigger", name, "status_code", result.StatusCode)
+}
+
+// render executes the url, body and header templates with the data
+func (r Request) render(data interface{}) (Request, error) {
+	var err error
+	if r.Url, err = renderTemplate(r.Url, data); err != nil {
+		return r, err
+	}
+	if r.Body, err = renderTemplate(r.Body, data); err != nil {
+		return r, err
+	}
+	header := make(map[string][]string, len(r.Header))
+	for key, values := range r.Header {
+		rendered := make([]string, 0, len(values))
+		for _, value := range values {
+			value, err = renderTemplate(value, data)
+			if err != nil {
+				return r, err
+			}
+			rendered = append(rendered, value)
+		}
+		header[key] = rendered
+	}
+	r.Header = header
+	return r, nil
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// validateTemplate executes the template with the zero data to report unknown fields at the path
+func validateTemplate(path, text string, data interface{}, errs *ValidationErrors) {
+	tmpl, err := parseTemplate(text)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, data)
+	}
+	if err != nil {
+		errs.add(path, "%s", err)
+	}
+}
+
+func renderTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	buffer := &bytes.Buffer{}
+	if err = tmpl.Execute(buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
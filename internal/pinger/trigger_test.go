@@ -0,0 +1,85 @@
+package pinger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"observer/internal/logger"
+	"observer/internal/settings"
+	"observer/pkg/mediator"
+)
+
+func testData() *Data {
+	loggerService := logger.New(nil, nil)
+	settingsService := settings.New(mediator.NewDispatcher(), loggerService, settings.NewSettingsRepo())
+	return New(mediator.NewDispatcher(), loggerService, settingsService, "")
+}
+
+func TestTriggerDoesNotBlockChecks(t *testing.T) {
+	release := make(chan struct{})
+	calls := make([]string, 0)
+	mutex := &sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mutex.Lock()
+		calls = append(calls, r.URL.Path)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	d := testData()
+	d.triggers = d.startTriggers()
+	trigger := &Trigger{
+		OnFail:       &Request{Url: server.URL + "/{{.Key}}/down", Timeout: time.Second},
+		OnSuccessful: &Request{Url: server.URL + "/{{.Key}}/up", Timeout: time.Second},
+	}
+	item := Item{Name: "item", Request: Request{Url: "http://localhost/", Trigger: trigger}}
+	ctx := context.Background()
+	start := time.Now()
+	d.trigger(ctx, item, ResponseResult{Successful: true, Date: start})
+	d.trigger(ctx, item, ResponseResult{Successful: false, Date: start.Add(time.Second)})
+	d.trigger(ctx, item, ResponseResult{Successful: true, Date: start.Add(2 * time.Second)})
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("trigger took %s, want it queued", elapsed)
+	}
+	close(release)
+	for _, queue := range d.triggers {
+		close(queue)
+	}
+	d.triggering.Wait()
+	want := []string{"/item/down", "/item/up"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+}
+
+func TestConfigTriggerTemplates(t *testing.T) {
+	cases := []struct {
+		name    string
+		trigger string
+		errors  []string
+	}{
+		{"valid", "{on_fail: {url: 'http://hook/{{.Key}}', body: '{{json .Error}}', header: {X-State: ['{{.State}}']}}}", nil},
+		{"unknown url field", "{on_fail: {url: 'http://hook/{{.Nme}}'}}", []string{"on_fail.url"}},
+		{"unknown body field", "{always: {url: 'http://hook/', body: '{{.Latncy}}'}}", []string{"always.body"}},
+		{"unknown header field", "{on_successful: {url: 'http://hook/', header: {X-Name: ['{{.Nam}}']}}}", []string{"on_successful.header.X-Name"}},
+	}
+	for _, c := range cases {
+		items := "      - name: web\n        request: {url: 'http://localhost/', trigger: " + c.trigger + "}\n"
+		paths := testConfigErrors(t, items)
+		if len(paths) != len(c.errors) {
+			t.Errorf("%s: errors %v, want %v", c.name, paths, c.errors)
+			continue
+		}
+		for i, path := range paths {
+			if path != "items_group[0].items[0].request.trigger."+c.errors[i] {
+				t.Errorf("%s: error %s, want %s", c.name, path, c.errors[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	models "observer/internal/domain/mediator"
+	"observer/internal/domain/repository"
+	"observer/pkg/requestFilter"
+)
+
+// fileRepo keeps settings in memory and writes every change to the JSON or YAML file
+type fileRepo struct {
+	path      string
+	storage   map[string]models.SettingsItem
+	mapSafety *sync.Mutex
+}
+
+// NewFileSettingsRepo loads settings from the file, YAML is used for .yml and .yaml files, JSON otherwise,
+// a missing file is created on the first change
+func NewFileSettingsRepo(path string) (repository.Settings, error) {
+	r := &fileRepo{
+		path:      path,
+		storage:   make(map[string]models.SettingsItem),
+		mapSafety: &sync.Mutex{},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	items := make([]models.SettingsItem, 0)
+	if r.yaml() {
+		err = yaml.Unmarshal(data, &items)
+	} else if len(strings.TrimSpace(string(data))) > 0 {
+		err = json.Unmarshal(data, &items)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	for _, item := range items {
+		if item.Name == "" {
+			return nil, fmt.Errorf("settings %s: item without name", path)
+		}
+		r.storage[item.Name] = item
+	}
+	return r, nil
+}
+
+// NewRepo returns the SQLite repo for "sqlite:" prefixed or .db, .sqlite and .sqlite3 paths,
+// the file repo for other paths or the RAM one when the path is empty
+func NewRepo(path string) (repository.Settings, error) {
+	if path == "" {
+		return NewSettingsRepo(), nil
+	}
+	if database, ok := sqlitePath(path); ok {
+		return NewSqliteSettingsRepo(database)
+	}
+	return NewFileSettingsRepo(path)
+}
+
+func (r *fileRepo) yaml() bool {
+	extension := strings.ToLower(filepath.Ext(r.path))
+	return extension == ".yml" || extension == ".yaml"
+}
+
+func (r *fileRepo) get(name string) (models.SettingsItem, error) {
+	if item, ok := r.storage[name]; ok {
+		return item, nil
+	}
+	return models.SettingsItem{}, fmt.Errorf("not found for name %v", name)
+}
+
+func (r *fileRepo) GetList(filter requestFilter.Filter) ([]models.SettingsItem, error) {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	return requestFilter.Apply(sortedItems(r.storage), filter, settingsInitiator)
+}
+
+func (r *fileRepo) Create(item models.SettingsItem) (models.SettingsItem, error) {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	if item.Name == "" {
+		return item, errors.New("name is required")
+	}
+	if _, err := r.get(item.Name); err == nil {
+		return item, fmt.Errorf("already exists for name %v", item.Name)
+	}
+	item.Id = nextId(r.storage, item.Id)
+	r.storage[item.Name] = item
+	if err := r.save(); err != nil {
+		delete(r.storage, item.Name)
+		return item, err
+	}
+	return item, nil
+}
+
+func (r *fileRepo) Update(item models.SettingsItem) (models.SettingsItem, error) {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	previous, err := r.get(item.Name)
+	if err != nil {
+		return item, err
+	}
+	r.storage[item.Name] = item
+	if err = r.save(); err != nil {
+		r.storage[item.Name] = previous
+		return item, err
+	}
+	return item, nil
+}
+
+func (r *fileRepo) Delete(name string) error {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	previous, err := r.get(name)
+	if err != nil {
+		return err
+	}
+	delete(r.storage, name)
+	if err = r.save(); err != nil {
+		r.storage[name] = previous
+		return err
+	}
+	return nil
+}
+
+// save writes items sorted by name to a temp file and renames it over the settings file,
+// the caller holds the mutex
+func (r *fileRepo) save() error {
+	items := sortedItems(r.storage)
+	var data []byte
+	var err error
+	if r.yaml() {
+		data, err = yaml.Marshal(items)
+	} else {
+		data, err = json.MarshalIndent(items, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("save settings: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("save settings: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err = temp.Write(data); err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), r.path)
+	}
+	if err != nil {
+		return fmt.Errorf("save settings: %w", err)
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package settings
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	models "observer/internal/domain/mediator"
@@ -9,6 +11,9 @@ import (
 	"observer/pkg/requestFilter"
 )
 
+// settingsInitiator is the field compared with the filter initiator by every repo
+const settingsInitiator = "user_id"
+
 type repo struct {
 	storage   map[string]models.SettingsItem
 	mapSafety *sync.Mutex
@@ -31,16 +36,21 @@ func (r *repo) get(name string) (models.SettingsItem, error) {
 func (r *repo) GetList(filter requestFilter.Filter) ([]models.SettingsItem, error) {
 	r.mapSafety.Lock()
 	defer r.mapSafety.Unlock()
-	name := ""
-	for _, filterItem := range filter.Filters {
-		for key, value := range filterItem.Data {
-			if key == "name" {
-				name = fmt.Sprintf("%v", &value)
-			}
-		}
+	return requestFilter.Apply(sortedItems(r.storage), filter, settingsInitiator)
+}
+
+func (r *repo) Create(item models.SettingsItem) (models.SettingsItem, error) {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	if item.Name == "" {
+		return item, errors.New("name is required")
 	}
-	founded, err := r.get(name)
-	return []models.SettingsItem{founded}, err
+	if _, err := r.get(item.Name); err == nil {
+		return item, fmt.Errorf("already exists for name %v", item.Name)
+	}
+	item.Id = nextId(r.storage, item.Id)
+	r.storage[item.Name] = item
+	return item, nil
 }
 
 func (r *repo) Update(item models.SettingsItem) (models.SettingsItem, error) {
@@ -53,3 +63,36 @@ func (r *repo) Update(item models.SettingsItem) (models.SettingsItem, error) {
 	r.storage[item.Name] = item
 	return item, nil
 }
+
+func (r *repo) Delete(name string) error {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	if _, err := r.get(name); err != nil {
+		return err
+	}
+	delete(r.storage, name)
+	return nil
+}
+
+// sortedItems returns stored items ordered by name, the default order of lists
+func sortedItems(storage map[string]models.SettingsItem) []models.SettingsItem {
+	items := make([]models.SettingsItem, 0, len(storage))
+	for _, item := range storage {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Name < items[j].Name
+	})
+	return items
+}
+
+// nextId keeps the item id or returns the one after the largest stored id
+func nextId(storage map[string]models.SettingsItem, id int) int {
+	if id != 0 {
+		return id
+	}
+	for _, item := range storage {
+		id = max(id, item.Id)
+	}
+	return id + 1
+}
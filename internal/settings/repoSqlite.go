@@ -0,0 +1,171 @@
+package settings
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+
+	models "observer/internal/domain/mediator"
+	"observer/internal/domain/repository"
+	"observer/pkg/requestFilter"
+)
+
+const settingsTable = "settings"
+
+const settingsSchema = `CREATE TABLE IF NOT EXISTS "settings" (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	"name" TEXT NOT NULL UNIQUE,
+	"value" TEXT NOT NULL DEFAULT '',
+	"group" TEXT NOT NULL DEFAULT '',
+	"type" TEXT NOT NULL DEFAULT '',
+	"data" TEXT NOT NULL DEFAULT '',
+	"user_id" INTEGER NOT NULL DEFAULT 0,
+	"title" TEXT NOT NULL DEFAULT '',
+	"description" TEXT NOT NULL DEFAULT ''
+)`
+
+// settingsColumns are the selected columns in the scan order of sqliteRepo.scan
+var settingsColumns = []string{"id", "name", "value", "group", "type", "data", "user_id", "title", "description"}
+
+// sqliteRepo keeps settings in the SQLite table, lists are queried by the filter
+type sqliteRepo struct {
+	db      *sql.DB
+	builder requestFilter.SqlBuilder
+}
+
+// NewSqliteSettingsRepo opens the SQLite database and creates the settings table when it is missing
+func NewSqliteSettingsRepo(path string) (repository.Settings, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	// a single connection serializes writes and keeps in-memory databases alive
+	db.SetMaxOpenConns(1)
+	if _, err = db.Exec(settingsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("settings %s: %w", path, err)
+	}
+	return &sqliteRepo{
+		db: db,
+		builder: requestFilter.SqlBuilder{
+			Dialect:   requestFilter.DialectSqlite,
+			Table:     settingsTable,
+			Columns:   requestFilter.ColumnsOf(models.SettingsItem{}),
+			Initiator: settingsInitiator,
+		},
+	}, nil
+}
+
+// sqlitePath returns the database path of "sqlite:" prefixed or .db, .sqlite and .sqlite3 paths
+func sqlitePath(path string) (string, bool) {
+	if trimmed, ok := strings.CutPrefix(path, "sqlite:"); ok {
+		return trimmed, true
+	}
+	lower := strings.ToLower(path)
+	for _, extension := range []string{".db", ".sqlite", ".sqlite3"} {
+		if strings.HasSuffix(lower, extension) {
+			return path, true
+		}
+	}
+	return path, false
+}
+
+func (r *sqliteRepo) GetList(filter requestFilter.Filter) ([]models.SettingsItem, error) {
+	if strings.TrimSpace(filter.Sort) == "" {
+		filter.Sort = "name"
+	}
+	query, args, err := r.builder.Select(filter, settingsColumns...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("settings list: %w", err)
+	}
+	defer rows.Close()
+	items := make([]models.SettingsItem, 0)
+	for rows.Next() {
+		item := models.SettingsItem{}
+		if err = rows.Scan(
+			&item.Id, &item.Name, &item.Value, &item.Group, &item.Type,
+			&item.Data, &item.UserId, &item.Title, &item.Description,
+		); err != nil {
+			return nil, fmt.Errorf("settings list: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("settings list: %w", err)
+	}
+	return items, nil
+}
+
+func (r *sqliteRepo) Create(item models.SettingsItem) (models.SettingsItem, error) {
+	if item.Name == "" {
+		return item, errors.New("name is required")
+	}
+	if r.exists(item.Name) {
+		return item, fmt.Errorf("already exists for name %v", item.Name)
+	}
+	var id interface{}
+	if item.Id != 0 {
+		id = item.Id
+	}
+	result, err := r.db.Exec(
+		`INSERT INTO "settings" ("id", "name", "value", "group", "type", "data", "user_id", "title", "description")
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, item.Name, item.Value, item.Group, item.Type, item.Data, item.UserId, item.Title, item.Description,
+	)
+	if err != nil {
+		return item, fmt.Errorf("create settings: %w", err)
+	}
+	inserted, err := result.LastInsertId()
+	if err != nil {
+		return item, fmt.Errorf("create settings: %w", err)
+	}
+	item.Id = int(inserted)
+	return item, nil
+}
+
+func (r *sqliteRepo) Update(item models.SettingsItem) (models.SettingsItem, error) {
+	result, err := r.db.Exec(
+		`UPDATE "settings" SET "value" = ?, "group" = ?, "type" = ?, "data" = ?, "user_id" = ?, "title" = ?, "description" = ?
+		WHERE "name" = ?`,
+		item.Value, item.Group, item.Type, item.Data, item.UserId, item.Title, item.Description, item.Name,
+	)
+	if err != nil {
+		return item, fmt.Errorf("update settings: %w", err)
+	}
+	if err = affected(result, item.Name); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+func (r *sqliteRepo) Delete(name string) error {
+	result, err := r.db.Exec(`DELETE FROM "settings" WHERE "name" = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete settings: %w", err)
+	}
+	return affected(result, name)
+}
+
+func (r *sqliteRepo) exists(name string) bool {
+	var id int
+	return r.db.QueryRow(`SELECT "id" FROM "settings" WHERE "name" = ?`, name).Scan(&id) == nil
+}
+
+// affected returns the not found error when the statement changed no rows
+func affected(result sql.Result, name string) error {
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("not found for name %v", name)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package settings
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	models "observer/internal/domain/mediator"
+	"observer/internal/domain/repository"
+	"observer/pkg/requestFilter"
+)
+
+func testRepos(t *testing.T) map[string]repository.Settings {
+	t.Helper()
+	dir := t.TempDir()
+	repos := map[string]repository.Settings{"ram": NewSettingsRepo()}
+	for name, path := range map[string]string{"file": "settings.yml", "sqlite": "settings.db"} {
+		repo, err := NewRepo(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		repos[name] = repo
+	}
+	items := []models.SettingsItem{
+		{Name: "OBSERVER_A", Value: "1", Group: "pinger", UserId: 3},
+		{Name: "observer_b", Value: "2", Group: "pinger", UserId: 3},
+		{Name: "OTHER", Value: "x", Group: "manager", UserId: 4},
+		{Name: "QUOTE", Value: "'; DROP TABLE settings; --", Group: "manager", UserId: 4},
+	}
+	for name, repo := range repos {
+		for _, item := range items {
+			if _, err := repo.Create(item); err != nil {
+				t.Fatalf("%s: create %s: %v", name, item.Name, err)
+			}
+		}
+	}
+	return repos
+}
+
+func TestReposFilterParity(t *testing.T) {
+	repos := testRepos(t)
+	cases := []struct {
+		name   string
+		filter func() requestFilter.Filter
+		want   []string
+	}{
+		{"all", func() requestFilter.Filter { return requestFilter.Filter{} }, []string{"OBSERVER_A", "OTHER", "QUOTE", "observer_b"}},
+		{"initiator", func() requestFilter.Filter {
+			f := requestFilter.GetSimpleFilter("like", "name", "%")
+			return f.ByInitiator(4)
+		}, []string{"OTHER", "QUOTE"}},
+		{"like ignores ascii case", func() requestFilter.Filter {
+			return requestFilter.GetSimpleFilter("like", "name", "observer_%")
+		}, []string{"OBSERVER_A", "observer_b"}},
+		{"equal is case sensitive", func() requestFilter.Filter {
+			return requestFilter.GetSimpleFilter("=", "name", "other")
+		}, []string{}},
+		{"value that looks like sql is data", func() requestFilter.Filter {
+			return requestFilter.GetSimpleFilter("=", "value", "'; DROP TABLE settings; --")
+		}, []string{"QUOTE"}},
+		{"in", func() requestFilter.Filter {
+			return requestFilter.GetSimpleFilter("in", "group", []interface{}{"manager"})
+		}, []string{"OTHER", "QUOTE"}},
+		{"empty in", func() requestFilter.Filter {
+			return requestFilter.GetSimpleFilter("in", "group", []interface{}{})
+		}, []string{}},
+		{"or", func() requestFilter.Filter {
+			return requestFilter.Filter{Operator: requestFilter.OperatorOr, Filters: []requestFilter.FilterItem{
+				requestFilter.GetSimpleFilterItem("=", "name", "OTHER"),
+				requestFilter.GetSimpleFilterItem("=", "value", "1"),
+			}}
+		}, []string{"OBSERVER_A", "OTHER"}},
+		{"json number", func() requestFilter.Filter {
+			return requestFilter.GetSimpleFilter(">", "user_id", json.Number("3"))
+		}, []string{"OTHER", "QUOTE"}},
+		{"sort and page", func() requestFilter.Filter {
+			return requestFilter.Filter{Sort: "-value", Limit: 1, Offset: 1}
+		}, []string{"observer_b"}},
+		{"offset without limit", func() requestFilter.Filter {
+			return requestFilter.Filter{Sort: "name", Offset: 3}
+		}, []string{"observer_b"}},
+		{"table is kept", func() requestFilter.Filter { return requestFilter.Filter{} }, []string{"OBSERVER_A", "OTHER", "QUOTE", "observer_b"}},
+	}
+	for _, c := range cases {
+		for name, repo := range repos {
+			items, err := repo.GetList(c.filter())
+			if err != nil {
+				t.Errorf("%s %s: %v", c.name, name, err)
+				continue
+			}
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.Name)
+			}
+			if len(got) != len(c.want) {
+				t.Errorf("%s %s: got %v, want %v", c.name, name, got, c.want)
+				continue
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("%s %s: got %v, want %v", c.name, name, got, c.want)
+					break
+				}
+			}
+		}
+	}
+	invalid := map[string]requestFilter.Filter{
+		// ordering against nil matches nothing in sql and everything in memory, every repo rejects it
+		"ordering against nil": requestFilter.GetSimpleFilter(">", "value", nil),
+		"injection key":        requestFilter.GetSimpleFilter("=", `name" = '' OR 1=1 --`, "x"),
+		"injection sort":       {Sort: "name; DROP TABLE settings"},
+	}
+	for filterName, filter := range invalid {
+		for name, repo := range repos {
+			if _, err := repo.GetList(filter); err == nil {
+				t.Errorf("%s %s: want an error", filterName, name)
+			}
+		}
+	}
+}
+
+func TestReposCreateParity(t *testing.T) {
+	for name, repo := range testRepos(t) {
+		if _, err := repo.Create(models.SettingsItem{Value: "1"}); err == nil {
+			t.Errorf("%s: empty name: want an error", name)
+		}
+		if _, err := repo.Create(models.SettingsItem{Name: "OTHER", Value: "y"}); err == nil {
+			t.Errorf("%s: duplicate name: want an error", name)
+		}
+	}
+}
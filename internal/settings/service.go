@@ -32,14 +32,14 @@ type settingsData struct {
 
 var _ = (services.Settings)(&settingsData{})
 
-func New(dispatcher *mediator.Dispatcher, logger *logger.Logger) services.Settings {
+func New(dispatcher *mediator.Dispatcher, logger *logger.Logger, repo repository.Settings) services.Settings {
 	logger.With("service", "settings")
 	app := &settingsData{
 		mapSafety:  &sync.Mutex{},
 		dispatcher: dispatcher,
 		cache:      cache.New(10*time.Minute, 20*time.Minute),
 		logger:     logger,
-		repo:       NewSettingsRepo(),
+		repo:       repo,
 	}
 
 	listener := Listener{
@@ -66,15 +66,46 @@ func (r *settingsData) GetList(filter requestFilter.Filter) ([]models.SettingsIt
 	return r.repo.GetList(filter)
 }
 
+// Create, Update and Delete invalidate the cached value after the repo is changed,
+// the mutex keeps a concurrent cache miss from caching the previous value
+func (r *settingsData) Create(item models.SettingsItem) (models.SettingsItem, error) {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	created, err := r.repo.Create(item)
+	if err == nil {
+		r.cache.Delete(item.Name)
+	}
+	return created, err
+}
+
 func (r *settingsData) Update(item models.SettingsItem) (models.SettingsItem, error) {
-	return r.repo.Update(item)
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	updated, err := r.repo.Update(item)
+	if err == nil {
+		r.cache.Delete(item.Name)
+	}
+	return updated, err
+}
+
+func (r *settingsData) Delete(name string) error {
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	err := r.repo.Delete(name)
+	if err == nil {
+		r.cache.Delete(name)
+	}
+	return err
 }
 
 func (r *settingsData) GetValue(name, defaultVal string) string {
-	if v, found := r.cache.Get(name); found {
-		if value, converted := v.(string); converted {
-			return value
-		}
+	if value, found := r.cachedValue(name); found {
+		return value
+	}
+	r.mapSafety.Lock()
+	defer r.mapSafety.Unlock()
+	if value, found := r.cachedValue(name); found {
+		return value
 	}
 	filter := requestFilter.GetSimpleFilter("=", "Name", name)
 	values, err := r.GetList(filter)
@@ -90,6 +121,15 @@ func (r *settingsData) GetValue(name, defaultVal string) string {
 	return defaultVal
 }
 
+func (r *settingsData) cachedValue(name string) (string, bool) {
+	if v, found := r.cache.Get(name); found {
+		if value, converted := v.(string); converted {
+			return value, true
+		}
+	}
+	return "", false
+}
+
 func (r *settingsData) GetValueInt(name string, defaultVal int) int {
 	value := r.GetValue(name, strconv.Itoa(defaultVal))
 	converted, _ := strconv.Atoi(value)
@@ -0,0 +1,63 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	models "observer/internal/domain/mediator"
+	"observer/internal/domain/repository"
+	"observer/internal/logger"
+	"observer/pkg/mediator"
+	"observer/pkg/requestFilter"
+)
+
+// slowListRepo returns lists read before the delay, like a slow query racing with a write
+type slowListRepo struct {
+	repository.Settings
+	delay time.Duration
+	read  chan struct{}
+}
+
+func (r slowListRepo) GetList(filter requestFilter.Filter) ([]models.SettingsItem, error) {
+	items, err := r.Settings.GetList(filter)
+	r.read <- struct{}{}
+	time.Sleep(r.delay)
+	return items, err
+}
+
+func TestSettingsCacheAfterWrite(t *testing.T) {
+	repo := slowListRepo{Settings: NewSettingsRepo(), delay: 50 * time.Millisecond, read: make(chan struct{}, 10)}
+	if _, err := repo.Settings.Create(models.SettingsItem{Name: "OBSERVER_X", Value: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	service := New(mediator.NewDispatcher(), logger.New(nil, nil), repo).(*settingsData)
+
+	done := make(chan string)
+	go func() {
+		done <- service.GetValue("OBSERVER_X", "")
+	}()
+	<-repo.read
+	if _, err := service.Update(models.SettingsItem{Name: "OBSERVER_X", Value: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if value := service.GetValue("OBSERVER_X", ""); value != "new" {
+		t.Errorf("value after update %q, want new", value)
+	}
+
+	if err := service.Delete("OBSERVER_X"); err != nil {
+		t.Fatal(err)
+	}
+	if value := service.GetValue("OBSERVER_X", "default"); value != "default" {
+		t.Errorf("value after delete %q, want default", value)
+	}
+	if err := service.Delete("OBSERVER_X"); err == nil {
+		t.Error("delete missing: want an error")
+	}
+	if _, err := service.Create(models.SettingsItem{Name: "OBSERVER_X", Value: "created"}); err != nil {
+		t.Fatal(err)
+	}
+	if value := service.GetValue("OBSERVER_X", "default"); value != "created" {
+		t.Errorf("value after create %q, want created", value)
+	}
+}
@@ -1,6 +1,7 @@
 package mediator
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -15,6 +16,9 @@ type Dispatcher struct {
 	events      map[EventName]Listener
 	afterEvents map[EventName]EventName
 	mutex       *sync.Mutex
+	closing     *sync.RWMutex
+	closed      bool
+	consumers   *sync.WaitGroup
 }
 
 func NewDispatcher() *Dispatcher {
@@ -23,8 +27,11 @@ func NewDispatcher() *Dispatcher {
 		events:      make(map[EventName]Listener),
 		afterEvents: make(map[EventName]EventName),
 		mutex:       &sync.Mutex{},
+		closing:     &sync.RWMutex{},
+		consumers:   &sync.WaitGroup{},
 	}
 	for i := 0; i < workers; i++ {
+		d.consumers.Add(1)
 		go d.consume()
 	}
 	return d
@@ -71,6 +78,11 @@ func (d *Dispatcher) Dispatch(name EventName, event interface{}) error {
 	if _, ok := d.GetEvent(name); !ok {
 		return fmt.Errorf("the '%s' event is not registered", name)
 	}
+	d.closing.RLock()
+	defer d.closing.RUnlock()
+	if d.closed {
+		return fmt.Errorf("the dispatcher is closed, the '%s' event is dropped", name)
+	}
 
 	d.jobs <- Job{EventName: name, EventType: event}
 
@@ -81,7 +93,29 @@ func (d *Dispatcher) Dispatch(name EventName, event interface{}) error {
 	return nil
 }
 
+// Shutdown stops accepting events and waits until the queued jobs are pushed or ctx is done
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.closing.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.jobs)
+	}
+	d.closing.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.consumers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("dispatcher shutdown, %d jobs left: %w", len(d.jobs), ctx.Err())
+	}
+}
+
 func (d *Dispatcher) consume() {
+	defer d.consumers.Done()
 	var listener Listener
 	for job := range d.jobs {
 		listener, _ = d.GetEvent(job.EventName)
@@ -0,0 +1,413 @@
+package requestFilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OperatorAnd = "and"
+	OperatorOr  = "or"
+)
+
+// Apply returns items matching the filter conditions and the initiator field, sorted by Sort,
+// one item per Group value and paged by Limit and Offset, items are structs, struct pointers
+// or maps with string keys, fields are found by name, db or json tag case-insensitively
+func Apply[T any](items []T, filter Filter, initiator string) ([]T, error) {
+	if filter.Initiator != 0 && initiator == "" {
+		return nil, errors.New("initiator is not supported")
+	}
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		matched, err := filter.Match(item)
+		if err == nil && matched && filter.Initiator != 0 {
+			matched, err = matchInitiator(item, initiator, filter.Initiator)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			result = append(result, item)
+		}
+	}
+	if err := sortItems(result, filter.Sort); err != nil {
+		return nil, err
+	}
+	result, err := groupItems(result, filter.Group)
+	if err != nil {
+		return nil, err
+	}
+	return pageItems(result, filter.Limit, filter.Offset), nil
+}
+
+// Match reports whether the item satisfies filters joined by the filter operator, "and" by default
+func (f Filter) Match(item interface{}) (bool, error) {
+	return matchItems(item, f.Operator, f.Filters)
+}
+
+func matchInitiator(item interface{}, initiator string, id int) (bool, error) {
+	actual, err := field(item, initiator)
+	if err != nil {
+		return false, err
+	}
+	result, err := compare(actual, id)
+	return result == 0, err
+}
+
+func matchItems(item interface{}, operator string, filterItems []FilterItem) (bool, error) {
+	or := strings.EqualFold(operator, OperatorOr)
+	if operator != "" && !or && !strings.EqualFold(operator, OperatorAnd) {
+		return false, fmt.Errorf("unknown operator %q", operator)
+	}
+	if len(filterItems) == 0 {
+		return true, nil
+	}
+	for _, filterItem := range filterItems {
+		matched, err := filterItem.match(item)
+		if err != nil {
+			return false, err
+		}
+		if matched == or {
+			return or, nil
+		}
+	}
+	return !or, nil
+}
+
+// match checks the nested group or every Data key by the condition, "=" by default
+func (fi FilterItem) match(item interface{}) (bool, error) {
+	if len(fi.Group.FilterItems) > 0 {
+		return matchItems(item, fi.Group.Operator, fi.Group.FilterItems)
+	}
+	keys := make([]string, 0, len(fi.Data))
+	for key := range fi.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		actual, err := field(item, key)
+		if err != nil {
+			return false, err
+		}
+		matched, err := matchCondition(fi.Condition, actual, fi.Data[key])
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", key, err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchCondition(condition string, actual, expected interface{}) (bool, error) {
+	operator := strings.ToLower(strings.TrimSpace(condition))
+	if isOrdering(operator) && normalize(expected) == nil {
+		return false, fmt.Errorf("%s expects a value, got nil", operator)
+	}
+	switch operator {
+	case "", "=", "==":
+		result, err := compare(actual, expected)
+		return result == 0, err
+	case "!=", "<>":
+		result, err := compare(actual, expected)
+		return result != 0, err
+	case "<":
+		result, err := compare(actual, expected)
+		return result < 0, err
+	case "<=":
+		result, err := compare(actual, expected)
+		return result <= 0, err
+	case ">":
+		result, err := compare(actual, expected)
+		return result > 0, err
+	case ">=":
+		result, err := compare(actual, expected)
+		return result >= 0, err
+	case "like":
+		return like(fmt.Sprint(actual), fmt.Sprint(expected))
+	case "in":
+		values := reflect.ValueOf(expected)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return false, fmt.Errorf("in expects a list, got %T", expected)
+		}
+		for i := 0; i < values.Len(); i++ {
+			// nil never matches in a list as in sql
+			value := values.Index(i).Interface()
+			if normalize(value) == nil {
+				continue
+			}
+			if result, err := compare(actual, value); err == nil && result == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown condition %q", condition)
+}
+
+// isOrdering reports whether the condition compares by order, such conditions do not accept nil
+func isOrdering(operator string) bool {
+	switch operator {
+	case "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// like matches the SQL pattern, % is any string and _ is any character,
+// ASCII letters are compared case-insensitively as SQLite does
+func like(value, pattern string) (bool, error) {
+	value, pattern = asciiLower(value), asciiLower(pattern)
+	expression := strings.NewReplacer(`%`, `.*`, `_`, `.`).Replace(regexp.QuoteMeta(pattern))
+	re, err := regexp.Compile("^(?s:" + expression + ")$")
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value), nil
+}
+
+func asciiLower(value string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, value)
+}
+
+// compare returns -1, 0 or 1, expected is converted to the type of actual
+func compare(actual, expected interface{}) (int, error) {
+	actual, expected = normalize(actual), normalize(expected)
+	if expected == nil && actual != nil {
+		// only nil equals nil as IS NULL does in sql
+		return 1, nil
+	}
+	switch a := actual.(type) {
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			parsed, err := strconv.ParseFloat(fmt.Sprint(expected), 64)
+			if err != nil {
+				return 0, fmt.Errorf("number expected, got %v", expected)
+			}
+			e = parsed
+		}
+		return compareOrdered(a, e), nil
+	case bool:
+		e, ok := expected.(bool)
+		if !ok {
+			parsed, err := strconv.ParseBool(fmt.Sprint(expected))
+			if err != nil {
+				return 0, fmt.Errorf("bool expected, got %v", expected)
+			}
+			e = parsed
+		}
+		if a == e {
+			return 0, nil
+		}
+		if a {
+			return 1, nil
+		}
+		return -1, nil
+	case time.Time:
+		e, ok := expected.(time.Time)
+		if !ok {
+			parsed, err := time.Parse(time.RFC3339, fmt.Sprint(expected))
+			if err != nil {
+				return 0, fmt.Errorf("RFC 3339 date expected, got %v", expected)
+			}
+			e = parsed
+		}
+		return a.Compare(e), nil
+	case nil:
+		if expected == nil {
+			return 0, nil
+		}
+		return -1, nil
+	}
+	return strings.Compare(fmt.Sprint(actual), fmt.Sprint(expected)), nil
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// normalize converts numbers to float64 and dereferences pointers
+func normalize(value interface{}) interface{} {
+	if number, ok := value.(json.Number); ok {
+		if converted, err := number.Float64(); err == nil {
+			return converted
+		}
+		return number.String()
+	}
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Pointer || reflected.Kind() == reflect.Interface {
+		if reflected.IsNil() {
+			return nil
+		}
+		reflected = reflected.Elem()
+	}
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(reflected.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(reflected.Uint())
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float()
+	case reflect.Invalid:
+		return nil
+	}
+	return reflected.Interface()
+}
+
+// field returns the struct field or the map value by the key
+func field(item interface{}, key string) (interface{}, error) {
+	value := reflect.ValueOf(item)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, fmt.Errorf("field %s of nil item", key)
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			break
+		}
+		for _, mapKey := range value.MapKeys() {
+			if strings.EqualFold(mapKey.String(), key) {
+				return value.MapIndex(mapKey).Interface(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown field %s", key)
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			structField := valueType.Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			if strings.EqualFold(structField.Name, key) ||
+				strings.EqualFold(tagName(structField.Tag.Get("db")), key) ||
+				strings.EqualFold(tagName(structField.Tag.Get("json")), key) {
+				return value.Field(i).Interface(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown field %s", key)
+	}
+	return nil, fmt.Errorf("field %s of %T is not supported", key, item)
+}
+
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// sortFields parses "name,-id" or "name asc, id desc" into fields and descending flags
+func sortFields(sortBy string) ([]string, []bool) {
+	fields := make([]string, 0)
+	descending := make([]bool, 0)
+	for _, part := range strings.Split(sortBy, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 {
+			continue
+		}
+		name := words[0]
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimLeft(name, "+-")
+		if len(words) > 1 && strings.EqualFold(words[1], "desc") {
+			desc = true
+		}
+		fields = append(fields, name)
+		descending = append(descending, desc)
+	}
+	return fields, descending
+}
+
+func sortItems[T any](items []T, sortBy string) error {
+	fields, descending := sortFields(sortBy)
+	if len(fields) == 0 || len(items) == 0 {
+		return nil
+	}
+	keys := make([][]interface{}, len(items))
+	for i, item := range items {
+		keys[i] = make([]interface{}, len(fields))
+		for j, name := range fields {
+			value, err := field(item, name)
+			if err != nil {
+				return err
+			}
+			keys[i][j] = value
+		}
+	}
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		for j := range fields {
+			result, err := compare(keys[indexes[a]][j], keys[indexes[b]][j])
+			if err != nil || result == 0 {
+				continue
+			}
+			return (result < 0) != descending[j]
+		}
+		return false
+	})
+	sorted := make([]T, len(items))
+	for i, index := range indexes {
+		sorted[i] = items[index]
+	}
+	copy(items, sorted)
+	return nil
+}
+
+// groupItems keeps the first item of every distinct group field value
+func groupItems[T any](items []T, group string) ([]T, error) {
+	if group == "" {
+		return items, nil
+	}
+	seen := make(map[string]bool)
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		value, err := field(item, group)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprint(normalize(value))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func pageItems[T any](items []T, limit, offset uint) []T {
+	if offset >= uint(len(items)) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < uint(len(items)) {
+		items = items[:limit]
+	}
+	return items
+}
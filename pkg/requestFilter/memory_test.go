@@ -0,0 +1,142 @@
+package requestFilter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type memoryItem struct {
+	Id      int       `db:"id" json:"id"`
+	Name    string    `db:"name" json:"name"`
+	Group   string    `db:"group_name" json:"group"`
+	Score   float64   `json:"score"`
+	Enabled bool      `json:"enabled"`
+	Owner   *int      `db:"user_id" json:"owner"`
+	Created time.Time `json:"created"`
+	secret  string
+}
+
+func memoryItems() []memoryItem {
+	owner := 7
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []memoryItem{
+		{Id: 1, Name: "Alpha", Group: "a", Score: 1.5, Enabled: true, Owner: &owner, Created: date},
+		{Id: 2, Name: "beta", Group: "b", Score: 3, Created: date.Add(time.Hour)},
+		{Id: 3, Name: "gamma_1", Group: "a", Score: 2, Enabled: true, Owner: &owner, Created: date.Add(2 * time.Hour)},
+		{Id: 4, Name: "delta", Group: "c", Score: 3, Created: date.Add(3 * time.Hour)},
+	}
+}
+
+func memoryIds(items []memoryItem) []int {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{"empty", Filter{}, []int{1, 2, 3, 4}},
+		{"equal by field name", GetSimpleFilter("", "Name", "beta"), []int{2}},
+		{"equal by db tag", GetSimpleFilter("==", "group_name", "a"), []int{1, 3}},
+		{"equal by json tag case-insensitively", GetSimpleFilter("=", "GROUP", "c"), []int{4}},
+		{"not equal", GetSimpleFilter("<>", "group", "a"), []int{2, 4}},
+		{"json number", GetSimpleFilter(">=", "score", json.Number("2")), []int{2, 3, 4}},
+		{"int against float", GetSimpleFilter("<", "id", 2.5), []int{1, 2}},
+		{"string number", GetSimpleFilter(">", "score", "2"), []int{2, 4}},
+		{"bool", GetSimpleFilter("=", "enabled", "true"), []int{1, 3}},
+		{"date", GetSimpleFilter(">", "created", "2024-01-01T01:30:00Z"), []int{3, 4}},
+		{"nil pointer", GetSimpleFilter("=", "owner", nil), []int{2, 4}},
+		{"not nil pointer", GetSimpleFilter("!=", "owner", nil), []int{1, 3}},
+		{"pointer value", GetSimpleFilter("=", "owner", 7), []int{1, 3}},
+		{"like any string", GetSimpleFilter("like", "name", "%a"), []int{1, 2, 4}},
+		{"like one character", GetSimpleFilter("like", "name", "gamma__"), []int{3}},
+		{"like ignores case", GetSimpleFilter("like", "name", "ALPHA"), []int{1}},
+		{"like quotes regex", GetSimpleFilter("like", "name", "a.pha"), []int{}},
+		{"in", GetSimpleFilter("in", "id", []int{2, 4, 9}), []int{2, 4}},
+		{"in json values", GetSimpleFilter("in", "name", []interface{}{"beta", "delta"}), []int{2, 4}},
+		{"in skips nil", GetSimpleFilter("in", "owner", []interface{}{nil}), []int{}},
+		{"several keys", Filter{Filters: []FilterItem{{Condition: "=", Data: map[string]interface{}{"group": "a", "id": 3}}}}, []int{3}},
+		{"and", Filter{Filters: []FilterItem{
+			GetSimpleFilterItem("=", "group", "a"),
+			GetSimpleFilterItem(">", "score", 1.5),
+		}}, []int{3}},
+		{"or", Filter{Operator: OperatorOr, Filters: []FilterItem{
+			GetSimpleFilterItem("=", "group", "c"),
+			GetSimpleFilterItem("=", "name", "beta"),
+		}}, []int{2, 4}},
+		{"nested group", Filter{Operator: "AND", Filters: []FilterItem{
+			GetSimpleFilterItem("=", "enabled", false),
+			{Group: FilterItemGroup{Operator: "OR", FilterItems: []FilterItem{
+				GetSimpleFilterItem("=", "id", 1),
+				GetSimpleFilterItem("=", "score", 3),
+			}}},
+		}}, []int{2, 4}},
+		{"initiator", Filter{Initiator: 7}, []int{1, 3}},
+		{"sort descending", Filter{Sort: "-score,id"}, []int{2, 4, 3, 1}},
+		{"sort words", Filter{Sort: "score desc, id desc"}, []int{4, 2, 3, 1}},
+		{"sort strings", Filter{Sort: "name"}, []int{1, 2, 4, 3}},
+		{"group keeps first", Filter{Sort: "id", Group: "group"}, []int{1, 2, 4}},
+		{"limit", Filter{Limit: 2}, []int{1, 2}},
+		{"offset", Filter{Offset: 3}, []int{4}},
+		{"offset after end", Filter{Offset: 10}, []int{}},
+		{"page", Filter{Sort: "-id", Limit: 2, Offset: 1}, []int{3, 2}},
+	}
+	for _, c := range cases {
+		got, err := Apply(memoryItems(), c.filter, "owner")
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if ids := memoryIds(got); !reflect.DeepEqual(ids, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, ids, c.want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	cases := map[string]Filter{
+		"unknown field":       GetSimpleFilter("=", "missing", 1),
+		"unexported field":    GetSimpleFilter("=", "secret", ""),
+		"unknown condition":   GetSimpleFilter("~", "name", "a"),
+		"unknown operator":    {Operator: "xor", Filters: []FilterItem{GetSimpleFilterItem("=", "id", 1)}},
+		"in without list":     GetSimpleFilter("in", "id", 1),
+		"ordering nil":        GetSimpleFilter(">", "owner", nil),
+		"number expected":     GetSimpleFilter(">", "score", "high"),
+		"date expected":       GetSimpleFilter(">", "created", "yesterday"),
+		"unknown sort field":  {Sort: "missing"},
+		"unknown group field": {Group: "missing"},
+	}
+	for name, filter := range cases {
+		if _, err := Apply(memoryItems(), filter, "owner"); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+	if _, err := Apply(memoryItems(), Filter{Initiator: 1}, ""); err == nil {
+		t.Error("initiator without field: want an error")
+	}
+}
+
+func TestApplyMaps(t *testing.T) {
+	items := []map[string]interface{}{
+		{"Name": "a", "count": 2},
+		{"Name": "b", "count": 1},
+	}
+	got, err := Apply(items, Filter{Sort: "count", Filters: []FilterItem{GetSimpleFilterItem("like", "name", "_")}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0]["Name"] != "b" {
+		t.Errorf("got %v", got)
+	}
+	if _, err = Apply([]int{1}, GetSimpleFilter("=", "id", 1), ""); err == nil {
+		t.Error("not a struct: want an error")
+	}
+}
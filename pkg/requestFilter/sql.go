@@ -0,0 +1,333 @@
+package requestFilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Dialect string
+
+const (
+	DialectSqlite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SqlBuilder translates filters into parameterized queries of the table,
+// only keys of Columns are accepted as filter and sort fields, Initiator is the column
+// compared with Filter.Initiator when it is set
+type SqlBuilder struct {
+	Dialect   Dialect
+	Table     string
+	Columns   map[string]string
+	Initiator string
+}
+
+// ColumnsOf returns the column whitelist of the struct db tags,
+// the columns are available by the field name, the db and the json tag
+func ColumnsOf(item interface{}) map[string]string {
+	columns := make(map[string]string)
+	itemType := reflect.TypeOf(item)
+	for itemType.Kind() == reflect.Pointer {
+		itemType = itemType.Elem()
+	}
+	for i := 0; i < itemType.NumField(); i++ {
+		structField := itemType.Field(i)
+		column := tagName(structField.Tag.Get("db"))
+		if !structField.IsExported() || column == "" {
+			continue
+		}
+		for _, key := range []string{structField.Name, column, tagName(structField.Tag.Get("json"))} {
+			if key != "" {
+				columns[strings.ToLower(key)] = column
+			}
+		}
+	}
+	return columns
+}
+
+// sqlQuery collects the query text and its arguments
+type sqlQuery struct {
+	dialect Dialect
+	text    *strings.Builder
+	args    []interface{}
+}
+
+func (q *sqlQuery) write(parts ...string) {
+	for _, part := range parts {
+		q.text.WriteString(part)
+	}
+}
+
+// bind adds the argument and returns its placeholder
+func (q *sqlQuery) bind(value interface{}) string {
+	q.args = append(q.args, sqlValue(value))
+	if q.dialect == DialectPostgres {
+		return "$" + strconv.Itoa(len(q.args))
+	}
+	return "?"
+}
+
+// Select returns the query of fields (all columns when empty) matching the filter,
+// sorted and paged by the filter
+func (b SqlBuilder) Select(filter Filter, fields ...string) (string, []interface{}, error) {
+	if err := b.validate(); err != nil {
+		return "", nil, err
+	}
+	if filter.Group != "" {
+		return "", nil, errors.New("group is not supported by sql queries")
+	}
+	query := &sqlQuery{dialect: b.Dialect, text: &strings.Builder{}}
+	selected := "*"
+	if len(fields) > 0 {
+		columns := make([]string, 0, len(fields))
+		for _, name := range fields {
+			column, err := b.column(name)
+			if err != nil {
+				return "", nil, err
+			}
+			columns = append(columns, column)
+		}
+		selected = strings.Join(columns, ", ")
+	}
+	query.write("SELECT ", selected, " FROM ", quoteIdentifier(b.Table))
+	if err := b.where(query, filter); err != nil {
+		return "", nil, err
+	}
+	if err := b.orderBy(query, filter.Sort); err != nil {
+		return "", nil, err
+	}
+	switch {
+	case filter.Limit > 0:
+		query.write(" LIMIT ", query.bind(filter.Limit))
+	case filter.Offset > 0 && b.Dialect == DialectSqlite:
+		// sqlite accepts OFFSET only after LIMIT, -1 is no limit
+		query.write(" LIMIT -1")
+	}
+	if filter.Offset > 0 {
+		query.write(" OFFSET ", query.bind(filter.Offset))
+	}
+	return query.text.String(), query.args, nil
+}
+
+// Where returns the condition of the filter without the WHERE keyword, "1=1" for empty filters
+func (b SqlBuilder) Where(filter Filter) (string, []interface{}, error) {
+	if err := b.validate(); err != nil {
+		return "", nil, err
+	}
+	query := &sqlQuery{dialect: b.Dialect, text: &strings.Builder{}}
+	if err := b.conditions(query, filter); err != nil {
+		return "", nil, err
+	}
+	if query.text.Len() == 0 {
+		return "1=1", nil, nil
+	}
+	return query.text.String(), query.args, nil
+}
+
+func (b SqlBuilder) validate() error {
+	if b.Dialect != DialectSqlite && b.Dialect != DialectPostgres {
+		return fmt.Errorf("unknown sql dialect %q", b.Dialect)
+	}
+	if !identifierRegex.MatchString(b.Table) {
+		return fmt.Errorf("invalid table name %q", b.Table)
+	}
+	return nil
+}
+
+// column returns the quoted whitelisted column of the filter key
+func (b SqlBuilder) column(key string) (string, error) {
+	column, ok := b.Columns[strings.ToLower(key)]
+	if !ok {
+		return "", fmt.Errorf("unknown field %s", key)
+	}
+	if !identifierRegex.MatchString(column) {
+		return "", fmt.Errorf("invalid column name %q", column)
+	}
+	return quoteIdentifier(column), nil
+}
+
+func (b SqlBuilder) where(query *sqlQuery, filter Filter) error {
+	conditions := &sqlQuery{dialect: query.dialect, text: &strings.Builder{}, args: query.args}
+	if err := b.conditions(conditions, filter); err != nil {
+		return err
+	}
+	if conditions.text.Len() > 0 {
+		query.write(" WHERE ", conditions.text.String())
+	}
+	query.args = conditions.args
+	return nil
+}
+
+// conditions writes the filter items joined by the operator and the initiator condition
+func (b SqlBuilder) conditions(query *sqlQuery, filter Filter) error {
+	if err := b.items(query, filter.Operator, filter.Filters); err != nil {
+		return err
+	}
+	if filter.Initiator == 0 {
+		return nil
+	}
+	if b.Initiator == "" {
+		return errors.New("initiator is not supported")
+	}
+	if !identifierRegex.MatchString(b.Initiator) {
+		return fmt.Errorf("invalid initiator column %q", b.Initiator)
+	}
+	if query.text.Len() > 0 {
+		text := query.text.String()
+		query.text.Reset()
+		query.write("(", text, ") AND ")
+	}
+	query.write(quoteIdentifier(b.Initiator), " = ", query.bind(filter.Initiator))
+	return nil
+}
+
+func (b SqlBuilder) items(query *sqlQuery, operator string, filterItems []FilterItem) error {
+	joiner := " AND "
+	switch {
+	case strings.EqualFold(operator, OperatorOr):
+		joiner = " OR "
+	case operator != "" && !strings.EqualFold(operator, OperatorAnd):
+		return fmt.Errorf("unknown operator %q", operator)
+	}
+	for i, filterItem := range filterItems {
+		if i > 0 {
+			query.write(joiner)
+		}
+		if len(filterItem.Group.FilterItems) > 0 {
+			query.write("(")
+			if err := b.items(query, filterItem.Group.Operator, filterItem.Group.FilterItems); err != nil {
+				return err
+			}
+			query.write(")")
+			continue
+		}
+		if err := b.item(query, filterItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// item writes every Data key compared by the condition, keys are joined by AND
+func (b SqlBuilder) item(query *sqlQuery, filterItem FilterItem) error {
+	keys := make([]string, 0, len(filterItem.Data))
+	for key := range filterItem.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		query.write("1=1")
+		return nil
+	}
+	query.write("(")
+	for i, key := range keys {
+		if i > 0 {
+			query.write(" AND ")
+		}
+		column, err := b.column(key)
+		if err != nil {
+			return err
+		}
+		if err = writeCondition(query, column, filterItem.Condition, filterItem.Data[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	query.write(")")
+	return nil
+}
+
+func writeCondition(query *sqlQuery, column, condition string, value interface{}) error {
+	operator := strings.ToLower(strings.TrimSpace(condition))
+	switch operator {
+	case "", "==":
+		operator = "="
+	case "<>":
+		operator = "!="
+	}
+	switch operator {
+	case "=", "!=":
+		if normalize(value) == nil {
+			query.write(column, map[string]string{"=": " IS NULL", "!=": " IS NOT NULL"}[operator])
+			return nil
+		}
+		query.write(column, " ", operator, " ", query.bind(value))
+	case "<", "<=", ">", ">=":
+		if normalize(value) == nil {
+			return fmt.Errorf("%s expects a value, got nil", operator)
+		}
+		query.write(column, " ", operator, " ", query.bind(value))
+	case "like":
+		// like is case-insensitive as in the memory evaluator, sqlite LIKE already is
+		keyword := " LIKE "
+		if query.dialect == DialectPostgres {
+			keyword = " ILIKE "
+		}
+		query.write(column, keyword, query.bind(fmt.Sprint(value)))
+	case "in":
+		values := reflect.ValueOf(value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return fmt.Errorf("in expects a list, got %T", value)
+		}
+		if values.Len() == 0 {
+			query.write("1=0")
+			return nil
+		}
+		placeholders := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			placeholders = append(placeholders, query.bind(values.Index(i).Interface()))
+		}
+		query.write(column, " IN (", strings.Join(placeholders, ", "), ")")
+	default:
+		return fmt.Errorf("unknown condition %q", condition)
+	}
+	return nil
+}
+
+func (b SqlBuilder) orderBy(query *sqlQuery, sortBy string) error {
+	fields, descending := sortFields(sortBy)
+	for i, name := range fields {
+		column, err := b.column(name)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			query.write(" ORDER BY ")
+		} else {
+			query.write(", ")
+		}
+		query.write(column)
+		if descending[i] {
+			query.write(" DESC")
+		} else {
+			query.write(" ASC")
+		}
+	}
+	return nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + name + `"`
+}
+
+// sqlValue converts json numbers to driver values
+func sqlValue(value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if converted, err := number.Int64(); err == nil {
+		return converted
+	}
+	if converted, err := number.Float64(); err == nil {
+		return converted
+	}
+	return number.String()
+}
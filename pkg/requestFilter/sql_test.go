@@ -0,0 +1,192 @@
+package requestFilter_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"observer/pkg/requestFilter"
+)
+
+// sqlItem is a row of the settings table, fields without the db tag are not columns
+type sqlItem struct {
+	Id          int    `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Group       string `db:"group" json:"group"`
+	UserId      int    `db:"user_id" json:"user_id"`
+	Title       string `db:"title" json:"title"`
+	Description string `db:"description" json:"description"`
+	Password    string `json:"password"`
+	secret      string `db:"secret"`
+}
+
+func settingsBuilder(dialect requestFilter.Dialect) requestFilter.SqlBuilder {
+	return requestFilter.SqlBuilder{
+		Dialect:   dialect,
+		Table:     "settings",
+		Columns:   requestFilter.ColumnsOf(sqlItem{}),
+		Initiator: "user_id",
+	}
+}
+
+func TestSqlRejectsUnknownFields(t *testing.T) {
+	builder := settingsBuilder(requestFilter.DialectSqlite)
+	cases := map[string]requestFilter.Filter{
+		"unknown key":        requestFilter.GetSimpleFilter("=", "password", "x"),
+		"unexported key":     requestFilter.GetSimpleFilter("=", "secret", "x"),
+		"injection key":      requestFilter.GetSimpleFilter("=", `name" = '' OR 1=1 --`, "x"),
+		"statement key":      requestFilter.GetSimpleFilter("=", "name; DROP TABLE settings", "x"),
+		"injection sort":     {Sort: "name; DROP TABLE settings"},
+		"quoted sort":        {Sort: `"name"`},
+		"unknown condition":  requestFilter.GetSimpleFilter("= 1 OR", "name", "x"),
+		"unknown operator":   {Operator: "xor", Filters: []requestFilter.FilterItem{requestFilter.GetSimpleFilterItem("=", "name", "x")}},
+		"in without list":    requestFilter.GetSimpleFilter("in", "name", "x"),
+		"ordering nil":       requestFilter.GetSimpleFilter(">=", "name", nil),
+		"group":              {Group: "name"},
+		"nested unknown key": {Filters: []requestFilter.FilterItem{{Group: requestFilter.FilterItemGroup{FilterItems: []requestFilter.FilterItem{requestFilter.GetSimpleFilterItem("=", "1=1", "x")}}}}},
+	}
+	for name, filter := range cases {
+		if query, args, err := builder.Select(filter); err == nil {
+			t.Errorf("%s: got %s %v, want an error", name, query, args)
+		}
+	}
+
+	invalid := []requestFilter.SqlBuilder{
+		{Dialect: requestFilter.DialectSqlite, Table: "settings; DROP TABLE x"},
+		{Dialect: "mysql", Table: "settings"},
+		{Dialect: requestFilter.DialectSqlite, Table: "settings", Columns: map[string]string{"name": `name"--`}},
+	}
+	for _, builder := range invalid {
+		if _, _, err := builder.Select(requestFilter.GetSimpleFilter("=", "name", "x")); err == nil {
+			t.Errorf("%+v: want an error", builder)
+		}
+	}
+
+	noInitiator := settingsBuilder(requestFilter.DialectSqlite)
+	noInitiator.Initiator = ""
+	filter := requestFilter.Filter{}
+	if _, _, err := noInitiator.Select(filter.ByInitiator(1)); err == nil {
+		t.Error("initiator without column: want an error")
+	}
+}
+
+func TestSqlSelect(t *testing.T) {
+	nested := requestFilter.Filter{
+		Operator: requestFilter.OperatorOr,
+		Filters: []requestFilter.FilterItem{
+			requestFilter.GetSimpleFilterItem("like", "name", "OBSERVER_%"),
+			{Group: requestFilter.FilterItemGroup{
+				Operator: requestFilter.OperatorAnd,
+				FilterItems: []requestFilter.FilterItem{
+					requestFilter.GetSimpleFilterItem("in", "Group", []string{"pinger", "manager"}),
+					{Group: requestFilter.FilterItemGroup{
+						Operator: requestFilter.OperatorOr,
+						FilterItems: []requestFilter.FilterItem{
+							requestFilter.GetSimpleFilterItem(">=", "id", json.Number("10")),
+							requestFilter.GetSimpleFilterItem("<", "id", 3),
+						},
+					}},
+				},
+			}},
+		},
+		Sort:      "-name, id",
+		Limit:     5,
+		Offset:    10,
+		Initiator: 7,
+	}
+	cases := []struct {
+		name    string
+		dialect requestFilter.Dialect
+		filter  requestFilter.Filter
+		query   string
+		args    []interface{}
+	}{
+		{
+			name:    "postgres nested groups",
+			dialect: requestFilter.DialectPostgres,
+			filter:  nested,
+			query: `SELECT * FROM "settings" WHERE (("name" ILIKE $1) OR (("group" IN ($2, $3)) AND (("id" >= $4) OR ("id" < $5))))` +
+				` AND "user_id" = $6 ORDER BY "name" DESC, "id" ASC LIMIT $7 OFFSET $8`,
+			args: []interface{}{"OBSERVER_%", "pinger", "manager", int64(10), 3, 7, uint(5), uint(10)},
+		},
+		{
+			name:    "sqlite nested groups",
+			dialect: requestFilter.DialectSqlite,
+			filter:  nested,
+			query: `SELECT * FROM "settings" WHERE (("name" LIKE ?) OR (("group" IN (?, ?)) AND (("id" >= ?) OR ("id" < ?))))` +
+				` AND "user_id" = ? ORDER BY "name" DESC, "id" ASC LIMIT ? OFFSET ?`,
+			args: []interface{}{"OBSERVER_%", "pinger", "manager", int64(10), 3, 7, uint(5), uint(10)},
+		},
+		{
+			name:    "empty",
+			dialect: requestFilter.DialectPostgres,
+			query:   `SELECT * FROM "settings"`,
+		},
+		{
+			name:    "empty in",
+			dialect: requestFilter.DialectPostgres,
+			filter:  requestFilter.GetSimpleFilter("in", "name", []string{}),
+			query:   `SELECT * FROM "settings" WHERE (1=0)`,
+		},
+		{
+			name:    "null",
+			dialect: requestFilter.DialectSqlite,
+			filter: requestFilter.Filter{Filters: []requestFilter.FilterItem{
+				requestFilter.GetSimpleFilterItem("=", "title", nil),
+				requestFilter.GetSimpleFilterItem("<>", "description", (*string)(nil)),
+			}},
+			query: `SELECT * FROM "settings" WHERE ("title" IS NULL) AND ("description" IS NOT NULL)`,
+		},
+		{
+			name:    "sqlite offset without limit",
+			dialect: requestFilter.DialectSqlite,
+			filter:  requestFilter.Filter{Offset: 2},
+			query:   `SELECT * FROM "settings" LIMIT -1 OFFSET ?`,
+			args:    []interface{}{uint(2)},
+		},
+		{
+			name:    "postgres offset without limit",
+			dialect: requestFilter.DialectPostgres,
+			filter:  requestFilter.Filter{Offset: 2},
+			query:   `SELECT * FROM "settings" OFFSET $1`,
+			args:    []interface{}{uint(2)},
+		},
+		{
+			name:    "initiator only",
+			dialect: requestFilter.DialectPostgres,
+			filter:  requestFilter.Filter{Initiator: 3},
+			query:   `SELECT * FROM "settings" WHERE "user_id" = $1`,
+			args:    []interface{}{3},
+		},
+	}
+	for _, c := range cases {
+		query, args, err := settingsBuilder(c.dialect).Select(c.filter)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if query != c.query {
+			t.Errorf("%s: query\n%s\nwant\n%s", c.name, query, c.query)
+		}
+		if len(args) != len(c.args) || (len(args) > 0 && !reflect.DeepEqual(args, c.args)) {
+			t.Errorf("%s: args %#v, want %#v", c.name, args, c.args)
+		}
+		if c.dialect == requestFilter.DialectPostgres && strings.Count(query, "$") != len(args) {
+			t.Errorf("%s: %d placeholders for %d args", c.name, strings.Count(query, "$"), len(args))
+		}
+	}
+}
+
+func TestSqlWhere(t *testing.T) {
+	builder := settingsBuilder(requestFilter.DialectPostgres)
+	where, args, err := builder.Where(requestFilter.Filter{})
+	if err != nil || where != "1=1" || len(args) != 0 {
+		t.Errorf("empty: %s %v %v", where, args, err)
+	}
+	filter := requestFilter.GetSimpleFilter("!=", "name", "a")
+	where, args, err = builder.Where(filter.ByInitiator(2))
+	if err != nil || where != `(("name" != $1)) AND "user_id" = $2` || !reflect.DeepEqual(args, []interface{}{"a", 2}) {
+		t.Errorf("initiator: %s %v %v", where, args, err)
+	}
+}